@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/audit"
+	mcpserver "github.com/zach-snell/bbkt/internal/mcp"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Show the audit log of mutating MCP tool calls",
+	Long: `Reads the JSONL audit log written by 'bbkt mcp' (including rotated files)
+and prints matching entries, oldest first.
+
+Examples:
+  bbkt audit --since 24h
+  bbkt audit --repo 'acme/*' --action merge
+  bbkt audit --outcome refused --json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, _ := cmd.Flags().GetString("file")
+		if path == "" {
+			path = os.Getenv("BBKT_AUDIT_LOG")
+		}
+		if path == "" || path == "off" {
+			var err error
+			if path, err = audit.DefaultPath(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		filter := audit.Filter{}
+		filter.Tool, _ = cmd.Flags().GetString("tool")
+		filter.Action, _ = cmd.Flags().GetString("action")
+		filter.Outcome, _ = cmd.Flags().GetString("outcome")
+		filter.Profile, _ = cmd.Flags().GetString("profile")
+
+		if repo, _ := cmd.Flags().GetString("repo"); repo != "" {
+			allow, err := mcpserver.ParseAllowlist(strings.Split(repo, ","))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			filter.Match = allow.Allows
+		}
+
+		if since, _ := cmd.Flags().GetString("since"); since != "" {
+			t, err := parseSince(since)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			filter.Since = t
+		}
+
+		entries, err := audit.Read(path, filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if limit, _ := cmd.Flags().GetInt("limit"); limit > 0 && len(entries) > limit {
+			entries = entries[len(entries)-limit:]
+		}
+
+		PrintOrJSON(cmd, entries, func() {
+			if len(entries) == 0 {
+				fmt.Println("No audit entries found.")
+				return
+			}
+			t := NewTable()
+			t.Header("Time", "Profile", "Tool", "Action", "Target", "Outcome", "Objects")
+			for _, e := range entries {
+				target := "-"
+				if e.Workspace != "" {
+					target = e.Workspace
+					if e.RepoSlug != "" {
+						target += "/" + e.RepoSlug
+					}
+				}
+				outcome := e.Outcome
+				if e.Error != "" {
+					outcome += ": " + Truncate(e.Error, 40)
+				}
+				t.Row(
+					FormatTime(e.Time.Local()),
+					orDash(e.Profile),
+					e.Tool,
+					e.Action,
+					target,
+					outcome,
+					formatObjects(e.Objects),
+				)
+			}
+			t.Flush()
+		})
+	},
+}
+
+// parseSince accepts a duration relative to now (e.g. "24h") or a date/timestamp.
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: expected a duration (24h) or a date (2006-01-02)", s)
+}
+
+func formatObjects(objects map[string]string) string {
+	if len(objects) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(objects))
+	for k, v := range objects {
+		parts = append(parts, k+"="+Truncate(v, 40))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	RootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().String("file", "", "Audit log path (default ~/.config/bbkt/audit.jsonl or BBKT_AUDIT_LOG)")
+	auditCmd.Flags().String("tool", "", "Filter by tool name (e.g. manage_pull_requests)")
+	auditCmd.Flags().String("action", "", "Filter by action (e.g. merge)")
+	auditCmd.Flags().String("outcome", "", "Filter by outcome: success, error or refused")
+	auditCmd.Flags().String("profile", "", "Filter by credential profile")
+	auditCmd.Flags().String("repo", "", "Filter by 'workspace/repo' glob patterns (comma-separated)")
+	auditCmd.Flags().String("since", "", "Only show entries newer than a duration (24h) or date (2006-01-02)")
+	auditCmd.Flags().Int("limit", 0, "Show only the most recent N entries")
+}
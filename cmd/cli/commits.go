@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+var commitsCmd = &cobra.Command{
+	Use:     "commits",
+	Aliases: []string{"commit"},
+	Short:   "Work with repository commits",
+}
+
+var commitStatusCmd = &cobra.Command{
+	Use:     "status",
+	Aliases: []string{"statuses"},
+	Short:   "List and report commit build statuses",
+}
+
+var commitStatusListCmd = &cobra.Command{
+	Use:   "list [workspace] [repo-slug] [commit]",
+	Short: "List the build statuses reported for a commit",
+	Args:  cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		client := getClient()
+		result, err := client.ListCommitStatuses(bitbucket.ListCommitStatusesArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			Commit:    trailing[0],
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		PrintOrJSON(cmd, result, func() {
+			if len(result.Values) == 0 {
+				fmt.Println("No build statuses reported.")
+				return
+			}
+			t := NewTable()
+			t.Header("State", "Key", "Name", "Description", "Updated")
+			for _, s := range result.Values {
+				t.Row(s.State, s.Key, Truncate(s.Name, 30), Truncate(s.Description, 40), FormatTime(s.UpdatedOn))
+			}
+			t.Flush()
+			PrintPaginationFooter(result.Size, result.Page, len(result.Values), result.Next != "")
+		})
+	},
+}
+
+var commitStatusSetCmd = &cobra.Command{
+	Use:   "set [workspace] [repo-slug] [commit]",
+	Short: "Report a build status on a commit",
+	Long: `Creates or updates the build status with --key on a commit, so a CI job can
+report each stage under the same key:
+
+  bbkt commits status set $COMMIT --key ci/test --state INPROGRESS --url $BUILD_URL
+  bbkt commits status set $COMMIT --key ci/test --state SUCCESSFUL
+
+--url is required the first time a key is reported.`,
+	Args: cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		key, _ := cmd.Flags().GetString("key")
+		state, _ := cmd.Flags().GetString("state")
+		if key == "" || state == "" {
+			fmt.Fprintln(os.Stderr, "Error: --key and --state are required")
+			os.Exit(1)
+		}
+		url, _ := cmd.Flags().GetString("url")
+		name, _ := cmd.Flags().GetString("name")
+		description, _ := cmd.Flags().GetString("description")
+		refname, _ := cmd.Flags().GetString("refname")
+
+		client := getClient()
+		result, err := client.SetCommitStatus(bitbucket.SetCommitStatusArgs{
+			Workspace:   workspace,
+			RepoSlug:    repoSlug,
+			Commit:      trailing[0],
+			Key:         key,
+			State:       state,
+			URL:         url,
+			Name:        name,
+			Description: description,
+			Refname:     refname,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		PrintOrJSON(cmd, result, func() {
+			fmt.Printf("Reported %s for %s on %s\n", result.State, result.Key, Truncate(trailing[0], 12))
+			if result.Name != "" {
+				KV("Name", result.Name)
+			}
+			KV("URL", result.URL)
+		})
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(commitsCmd)
+	commitsCmd.AddCommand(commitStatusCmd)
+	commitStatusCmd.AddCommand(commitStatusListCmd)
+	commitStatusCmd.AddCommand(commitStatusSetCmd)
+
+	commitStatusSetCmd.Flags().String("key", "", "Status key, unique per commit (e.g. the CI job name)")
+	commitStatusSetCmd.Flags().String("state", "", "SUCCESSFUL, FAILED, INPROGRESS or STOPPED")
+	commitStatusSetCmd.Flags().String("url", "", "Link to the build (required the first time a key is reported)")
+	commitStatusSetCmd.Flags().String("name", "", "Display name")
+	commitStatusSetCmd.Flags().String("description", "", "Short description of the result")
+	commitStatusSetCmd.Flags().String("refname", "", "Branch or tag the build ran for")
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// editorHint is appended to text opened in the editor and removed afterwards.
+const editorHint = "<!-- The first line is the title and the rest the description. Save an empty title to cancel. -->"
+
+// isTerminal reports whether stdin is an interactive terminal.
+func isTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// editTitleAndBody opens $VISUAL or $EDITOR (default vi) on the title and
+// body, and returns them as saved.
+func editTitleAndBody(title, body string) (string, string, error) {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	f, err := os.CreateTemp("", "bbkt-PR_EDITMSG-*.md")
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(f.Name())
+	_, err = fmt.Fprintf(f, "%s\n\n%s\n\n%s\n", title, strings.TrimSpace(body), editorHint)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	// The editor may carry arguments, e.g. "code --wait".
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", "", fmt.Errorf("editor %s failed: %v", editor, err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", "", err
+	}
+	text := strings.TrimSpace(strings.ReplaceAll(string(data), editorHint, ""))
+	title, body, _ = strings.Cut(text, "\n")
+	return strings.TrimSpace(title), strings.TrimSpace(body), nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
 )
 
 // outputJSON returns true if the user passed --json.
@@ -92,6 +93,15 @@ func FormatTime(t time.Time) string {
 	return t.Format("2006-01-02 15:04")
 }
 
+// FormatTimeAgo formats t like FormatTime followed by its age, e.g.
+// "2024-05-01 14:03 (3h ago)".
+func FormatTimeAgo(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return FormatTime(t) + " (" + bitbucket.TimeAgo(t, time.Now()) + ")"
+}
+
 // FormatTimePtr handles nil time pointers.
 func FormatTimePtr(t *time.Time) string {
 	if t == nil {
@@ -5,42 +5,268 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/audit"
 	"github.com/zach-snell/bbkt/internal/bitbucket"
 	mcpserver "github.com/zach-snell/bbkt/internal/mcp"
 )
 
-var port int
+var (
+	port            int
+	allowedRepos    []string
+	confirmFallback string
+	auditLogPath    string
+	auditMaxMB      int
+	multiTenant     bool
+	sessionTimeout  time.Duration
+	bindAddr        string
+	httpAuthToken   string
+	tlsCert         string
+	tlsKey          string
+	allowedOrigins  []string
+	shutdownTimeout time.Duration
+	responseBudget  int
+	responseTokens  int
+	defaultWS       string
+	defaultRepo     string
+	toolMode        string
+	scopeRefresh    time.Duration
+)
 
 var mcpCmd = &cobra.Command{
 	Use:   "mcp",
 	Short: "Start the Bitbucket MCP Server",
 	Long: `Starts the Model Context Protocol (MCP) server for Bitbucket.
 By default, this runs on stdio. You can provide a --port flag to
-run it using the HTTP Streamable transport.`,
+run it using the HTTP Streamable transport.
+
+Use --allow (or BITBUCKET_ALLOWED_REPOS) to restrict every tool to a set
+of 'workspace/repo' glob patterns, e.g. --allow 'acme/payments,acme/infra-*'.
+
+Destructive actions (deleting repositories, branches or files, merging or
+declining pull requests) ask the human to confirm via MCP elicitation.
+--confirm-fallback decides what happens when the client cannot elicit.
+
+Every mutating tool call is appended to a JSONL audit log
+(~/.config/bbkt/audit.jsonl by default, --audit-log=off to disable).
+Query it with 'bbkt audit'.
+
+With --multi-tenant (HTTP only) the server holds no credentials of its own:
+each session authenticates with its own Bitbucket token in the Authorization
+header ('Bearer <access token>' or Basic '<email>:<API token>') and gets its
+own client and scope-filtered tool set. Idle sessions are closed after
+--session-timeout.
+
+For production HTTP deployments, bind to a specific address with --bind,
+require a shared secret with --auth-token ('Authorization: Bearer <token>'
+or 'X-API-Key: <token>'; only X-API-Key in multi-tenant mode), serve TLS
+with --tls-cert/--tls-key and restrict browser origins with
+--allowed-origins. /healthz and /readyz are served without auth, and
+SIGTERM drains in-flight tool calls before exiting.
+
+Large diffs, step logs and files are truncated to --response-budget bytes
+(or --response-budget-tokens approximate tokens) at file or line boundaries;
+the response ends with a cursor the agent passes back to continue.
+
+When launched inside a Bitbucket checkout (or given --workspace/--repo or
+BITBUCKET_WORKSPACE/BITBUCKET_REPO_SLUG), tools default to that repository
+and workspace/repo_slug become optional arguments.
+
+--tool-mode=granular replaces each manage_* tool with one tool per action
+(e.g. pr_merge, pipeline_get_step_log) whose schema lists exactly the
+fields that action takes, which suits smaller models.
+
+Tools the token lacks scopes for are hidden. The server re-checks scopes
+every --scope-refresh and on SIGHUP, follows 'bbkt profile use' while
+running, and notifies clients when its tool list changes.
+
+Every tool also takes an optional 'profile' argument naming a stored
+profile (see 'bbkt profile') to act as for that call, e.g. when different
+clients' workspaces live under separate Atlassian accounts. Each profile
+is held to its own token scopes; manage_profiles lists the profiles.
+Not available with --multi-tenant.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runServer()
+		runServer(cmd)
 	},
 }
 
 func init() {
 	RootCmd.AddCommand(mcpCmd)
 	mcpCmd.Flags().IntVarP(&port, "port", "p", 0, "Port to listen on for HTTP Streamable transport")
+	mcpCmd.Flags().StringSliceVar(&allowedRepos, "allow", nil, "Restrict tools to these 'workspace/repo' glob patterns (comma-separated)")
+	mcpCmd.Flags().StringVar(&confirmFallback, "confirm-fallback", "", "Behaviour for destructive actions when the client lacks elicitation: refuse or allow (default refuse)")
+	mcpCmd.Flags().StringVar(&auditLogPath, "audit-log", "", "Path of the JSONL audit log, or 'off' to disable (default ~/.config/bbkt/audit.jsonl)")
+	mcpCmd.Flags().IntVar(&auditMaxMB, "audit-max-mb", 10, "Rotate the audit log when it exceeds this size in megabytes")
+	mcpCmd.Flags().BoolVar(&multiTenant, "multi-tenant", false, "Authenticate each HTTP session with its own Bitbucket credentials (requires --port)")
+	mcpCmd.Flags().DurationVar(&sessionTimeout, "session-timeout", 30*time.Minute, "Close HTTP sessions idle for longer than this (0 disables)")
+	mcpCmd.Flags().StringVar(&bindAddr, "bind", "", "Address to bind the HTTP transport to (default all interfaces)")
+	mcpCmd.Flags().StringVar(&httpAuthToken, "auth-token", "", "Require this token on HTTP requests as a Bearer token or X-API-Key header (env BBKT_MCP_AUTH_TOKEN)")
+	mcpCmd.Flags().StringVar(&tlsCert, "tls-cert", "", "TLS certificate file for the HTTP transport")
+	mcpCmd.Flags().StringVar(&tlsKey, "tls-key", "", "TLS private key file for the HTTP transport")
+	mcpCmd.Flags().StringSliceVar(&allowedOrigins, "allowed-origins", nil, "Browser origins allowed to call the HTTP transport (comma-separated, '*' for any; default loopback origins only)")
+	mcpCmd.Flags().DurationVar(&shutdownTimeout, "shutdown-timeout", 30*time.Second, "How long to wait for in-flight tool calls on shutdown")
+	mcpCmd.Flags().IntVar(&responseBudget, "response-budget", mcpserver.DefaultResponseBudget, "Maximum bytes of diff/log/file content per tool call, 0 for unlimited (env BBKT_RESPONSE_BUDGET)")
+	mcpCmd.Flags().IntVar(&responseTokens, "response-budget-tokens", 0, "Response budget in approximate tokens (overrides --response-budget)")
+	mcpCmd.Flags().StringVar(&defaultWS, "workspace", "", "Default workspace for tool calls (default: from the local git remote)")
+	mcpCmd.Flags().StringVar(&defaultRepo, "repo", "", "Default repository slug for tool calls (default: from the local git remote)")
+	mcpCmd.Flags().DurationVar(&scopeRefresh, "scope-refresh", 15*time.Minute, "Re-check token scopes and update the tool list this often (0 disables)")
+	mcpCmd.Flags().StringVar(&toolMode, "tool-mode", "", "Tool layout: unified (manage_* tools) or granular (one tool per action) (env BITBUCKET_TOOL_MODE)")
 }
 
-func runServer() {
+// openAuditLog opens the audit log selected by --audit-log or BBKT_AUDIT_LOG.
+func openAuditLog() *audit.Logger {
+	path := auditLogPath
+	if path == "" {
+		path = os.Getenv("BBKT_AUDIT_LOG")
+	}
+	if path == "off" {
+		return nil
+	}
+	if path == "" {
+		var err error
+		if path, err = audit.DefaultPath(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: audit log disabled: %v\n", err)
+			return nil
+		}
+	}
+
+	logger, err := audit.Open(path, int64(auditMaxMB)<<20, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return logger
+}
+
+// repoDefaults resolves the default repository from flags, then env vars, then
+// the git remote of the launch directory. A shared multi-tenant server has no
+// meaningful launch directory, so it only uses explicit configuration.
+func repoDefaults() mcpserver.RepoDefaults {
+	d := mcpserver.RepoDefaults{Workspace: defaultWS, RepoSlug: defaultRepo}
+	if d.Workspace == "" {
+		d.Workspace = os.Getenv("BITBUCKET_WORKSPACE")
+	}
+	if d.RepoSlug == "" {
+		d.RepoSlug = os.Getenv("BITBUCKET_REPO_SLUG")
+	}
+	if d.Workspace == "" && d.RepoSlug == "" && !multiTenant {
+		if ws, repo, err := bitbucket.GetLocalRepoInfo(); err == nil {
+			d.Workspace, d.RepoSlug = ws, repo
+		}
+	}
+	if d.Workspace == "" && d.RepoSlug != "" {
+		fmt.Fprintf(os.Stderr, "Error: a default repository requires a default workspace (--workspace)\n")
+		os.Exit(1)
+	}
+	return d
+}
+
+// serverOptions resolves the MCP server policy from flags, falling back to env vars.
+func serverOptions(cmd *cobra.Command) *mcpserver.Options {
+	patterns := allowedRepos
+	if len(patterns) == 0 {
+		if env := os.Getenv("BITBUCKET_ALLOWED_REPOS"); env != "" {
+			patterns = strings.Split(env, ",")
+		}
+	}
+
+	allowlist, err := mcpserver.ParseAllowlist(patterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if allowlist != nil {
+		fmt.Fprintf(os.Stderr, "Restricting tools to repositories matching: %s\n", allowlist)
+	}
+
+	fallbackName := confirmFallback
+	if fallbackName == "" {
+		fallbackName = os.Getenv("BITBUCKET_CONFIRM_FALLBACK")
+	}
+	fallback, err := mcpserver.ParseConfirmFallback(fallbackName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	budget := responseBudget
+	if !cmd.Flags().Changed("response-budget") {
+		if env := os.Getenv("BBKT_RESPONSE_BUDGET"); env != "" {
+			if budget, err = strconv.Atoi(env); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid BBKT_RESPONSE_BUDGET %q\n", env)
+				os.Exit(1)
+			}
+		}
+	}
+	if responseTokens > 0 {
+		budget = responseTokens * mcpserver.BytesPerToken
+	}
+
+	modeName := toolMode
+	if modeName == "" {
+		modeName = os.Getenv("BITBUCKET_TOOL_MODE")
+	}
+	mode, err := mcpserver.ParseToolMode(modeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	defaults := repoDefaults()
+	if !defaults.IsZero() {
+		fmt.Fprintf(os.Stderr, "Defaulting tool calls to: %s\n", defaults)
+		if !allowlist.Allows(defaults.Workspace, defaults.RepoSlug) && defaults.RepoSlug != "" {
+			fmt.Fprintf(os.Stderr, "Warning: default repository %s is outside the allowlist\n", defaults)
+		}
+	}
+
+	return &mcpserver.Options{
+		Allowlist:       allowlist,
+		ConfirmFallback: fallback,
+		AuditLog:        openAuditLog(),
+		ResponseBudget:  budget,
+		Defaults:        defaults,
+		ToolMode:        mode,
+	}
+}
+
+func runServer(cmd *cobra.Command) {
 	// Priority: env vars > stored credentials
 	username := os.Getenv("BITBUCKET_USERNAME")
 	password := os.Getenv("BITBUCKET_API_TOKEN")
 	token := os.Getenv("BITBUCKET_ACCESS_TOKEN")
 
+	opts := serverOptions(cmd)
+
+	if multiTenant {
+		if port == 0 {
+			fmt.Fprintf(os.Stderr, "Error: --multi-tenant requires --port\n")
+			os.Exit(1)
+		}
+		opts.Calls = &mcpserver.CallTracker{}
+		fmt.Printf("Starting multi-tenant Bitbucket MCP Server on %s (HTTP Streamable)\n", httpAddr())
+		serveHTTP(mcpserver.NewMultiTenantHandler(opts, sessionTimeout), opts.Calls)
+		return
+	}
+
+	if port != 0 {
+		opts.Calls = &mcpserver.CallTracker{}
+	}
+
+	opts.Refresher = &mcpserver.Refresher{}
+	opts.Profiles = &mcpserver.ProfilePool{}
 	var s *mcp.Server
 
 	if token != "" || (username != "" && password != "") {
-		s = mcpserver.New(username, password, token)
+		opts.Profile = "env"
+		opts.User = username
+		s = mcpserver.New(username, password, token, opts)
+		go watchCredentials(opts.Refresher, nil)
 	} else {
 		creds, err := bitbucket.LoadCredentials()
 		if err != nil {
@@ -54,7 +280,10 @@ func runServer() {
 
 		switch {
 		case creds.IsAPIToken() || creds.IsOAuth():
-			s = mcpserver.NewFromCredentials(creds)
+			opts.Profile = creds.ProfileName
+			opts.User = creds.Email
+			s = mcpserver.NewFromCredentials(creds, opts)
+			go watchCredentials(opts.Refresher, creds)
 		default:
 			fmt.Fprintf(os.Stderr, "Unknown auth type in stored credentials: %s\n", creds.AuthType)
 			os.Exit(1)
@@ -62,21 +291,10 @@ func runServer() {
 	}
 
 	if port != 0 {
-		fmt.Printf("Starting Bitbucket MCP Server on :%d (HTTP Streamable)\n", port)
-		handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		fmt.Printf("Starting Bitbucket MCP Server on %s (HTTP Streamable)\n", httpAddr())
+		serveHTTP(mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
 			return s
-		}, &mcp.StreamableHTTPOptions{JSONResponse: false})
-
-		srv := &http.Server{
-			Addr:              fmt.Sprintf(":%d", port),
-			Handler:           handler,
-			ReadHeaderTimeout: 3 * time.Second,
-		}
-
-		if err := srv.ListenAndServe(); err != nil {
-			fmt.Fprintf(os.Stderr, "HTTP server error: %v\n", err)
-			os.Exit(1)
-		}
+		}, &mcp.StreamableHTTPOptions{JSONResponse: false, SessionTimeout: sessionTimeout}), opts.Calls)
 	} else {
 		if err := s.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
 			fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	mcpserver "github.com/zach-snell/bbkt/internal/mcp"
+)
+
+// httpAddr returns the listen address from --bind and --port.
+func httpAddr() string {
+	return net.JoinHostPort(bindAddr, strconv.Itoa(port))
+}
+
+// serveHTTP runs the MCP handler behind the health, origin and auth checks
+// until SIGINT/SIGTERM, then drains in-flight tool calls and shuts down.
+func serveHTTP(handler http.Handler, calls *mcpserver.CallTracker) {
+	if (tlsCert == "") != (tlsKey == "") {
+		fmt.Fprintf(os.Stderr, "Error: --tls-cert and --tls-key must be set together\n")
+		os.Exit(1)
+	}
+
+	token := httpAuthToken
+	if token == "" {
+		token = os.Getenv("BBKT_MCP_AUTH_TOKEN")
+	}
+	if token == "" && bindAddr != "127.0.0.1" && bindAddr != "localhost" && bindAddr != "::1" {
+		fmt.Fprintf(os.Stderr, "Warning: HTTP transport is reachable beyond localhost without --auth-token\n")
+	}
+
+	var ready atomic.Bool
+	ready.Store(true)
+
+	// Health endpoints bypass auth so load balancers and probes can reach them.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.Handle("/", requireOrigin(allowedOrigins, requireAuthToken(token, !multiTenant, handler)))
+
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	srv := &http.Server{
+		Addr:              httpAddr(),
+		Handler:           mux,
+		ReadHeaderTimeout: 3 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if tlsCert != "" {
+			errCh <- srv.ListenAndServeTLS(tlsCert, tlsKey)
+		} else {
+			errCh <- srv.ListenAndServe()
+		}
+	}()
+
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-errCh:
+		fmt.Fprintf(os.Stderr, "HTTP server error: %v\n", err)
+		os.Exit(1)
+	case <-sigCtx.Done():
+	}
+
+	ready.Store(false)
+	fmt.Fprintf(os.Stderr, "Shutting down: draining %d in-flight tool call(s)\n", calls.InFlight())
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := calls.Drain(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %d tool call(s) still running at shutdown timeout\n", calls.InFlight())
+	}
+	// Long-lived SSE streams only end when their request context is cancelled.
+	cancelRequests()
+	if err := srv.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		_ = srv.Close()
+	}
+}
+
+// requireAuthToken rejects requests that do not present token as an X-API-Key
+// header or, when allowBearer is set, as an Authorization Bearer token.
+// An empty token disables the check.
+func requireAuthToken(token string, allowBearer bool, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented := r.Header.Get("X-API-Key")
+		if presented == "" && allowBearer {
+			if scheme, value, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "bearer") {
+				presented = strings.TrimSpace(value)
+			}
+		}
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bbkt"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireOrigin rejects browser requests whose Origin is not in origins, or,
+// when origins is empty, not a loopback address, so a web page the user visits
+// cannot drive a local server (DNS rebinding). Requests without an Origin
+// header (non-browser clients) are always allowed.
+func requireOrigin(origins []string, next http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(origins))
+	for _, o := range origins {
+		allowed[strings.TrimRight(strings.ToLower(strings.TrimSpace(o)), "/")] = true
+	}
+	ok := func(origin string) bool {
+		if len(allowed) == 0 {
+			return isLoopbackOrigin(origin)
+		}
+		return allowed["*"] || allowed[strings.ToLower(origin)]
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !ok(origin) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackOrigin reports whether origin is an http(s) origin on localhost or
+// a loopback address.
+func isLoopbackOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := u.Hostname()
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
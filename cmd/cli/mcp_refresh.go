@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+	mcpserver "github.com/zach-snell/bbkt/internal/mcp"
+)
+
+// credentialsPollInterval is how often the credentials file is checked for a
+// profile switch or re-authentication.
+const credentialsPollInterval = 5 * time.Second
+
+// watchCredentials keeps the running server's tools in step with its
+// credentials. It re-fetches scopes every --scope-refresh and on SIGHUP, and,
+// for stored credentials, switches to the active profile whenever the
+// credentials file changes (e.g. after 'bbkt profile use' or 'bbkt auth').
+func watchCredentials(refresher *mcpserver.Refresher, current *bitbucket.Credentials) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	var refreshTick <-chan time.Time
+	if scopeRefresh > 0 {
+		refreshTick = time.NewTicker(scopeRefresh).C
+	}
+
+	var pollTick <-chan time.Time
+	var path string
+	var modTime time.Time
+	if current != nil {
+		var err error
+		if path, err = bitbucket.CredentialsPath(); err == nil {
+			modTime = fileModTime(path)
+			pollTick = time.NewTicker(credentialsPollInterval).C
+		}
+	}
+
+	// reload re-reads stored credentials, refreshing when they differ from
+	// the current ones or when forced.
+	reload := func(force bool) {
+		creds, err := bitbucket.LoadCredentials()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: keeping current credentials: %v\n", err)
+			if force {
+				refresher.Refresh(nil)
+			}
+			return
+		}
+		if !force && sameCredentials(creds, current) {
+			return
+		}
+		if creds.ProfileName != current.ProfileName {
+			fmt.Fprintf(os.Stderr, "Switching to profile '%s'\n", creds.ProfileName)
+		}
+		current = creds
+		refresher.Refresh(creds)
+	}
+
+	for {
+		select {
+		case <-hup:
+			fmt.Fprintf(os.Stderr, "Refreshing token scopes\n")
+			if current != nil {
+				reload(true)
+			} else {
+				refresher.Refresh(nil)
+			}
+		case <-refreshTick:
+			refresher.Refresh(nil)
+		case <-pollTick:
+			if m := fileModTime(path); !m.Equal(modTime) {
+				modTime = m
+				reload(false)
+			}
+		}
+	}
+}
+
+// sameCredentials reports whether a and b authenticate as the same token with
+// the same recorded scopes.
+func sameCredentials(a, b *bitbucket.Credentials) bool {
+	return a.ProfileName == b.ProfileName &&
+		a.AuthType == b.AuthType &&
+		a.Email == b.Email &&
+		a.APIToken == b.APIToken &&
+		a.AccessToken == b.AccessToken &&
+		a.Scopes == b.Scopes
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
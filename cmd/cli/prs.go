@@ -1,10 +1,14 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/huh"
 	"github.com/spf13/cobra"
@@ -122,7 +126,7 @@ var prsGetCmd = &cobra.Command{
 				KV("Description", Truncate(result.Description, 80))
 			}
 			KVf("Comments", "%d", result.CommentCount)
-			KVf("Tasks", "%d", result.TaskCount)
+			KVf("Open Tasks", "%d", result.TaskCount)
 			if len(result.Reviewers) > 0 {
 				names := make([]string, len(result.Reviewers))
 				for i, r := range result.Reviewers {
@@ -140,7 +144,19 @@ var prsGetCmd = &cobra.Command{
 var prsCreateCmd = &cobra.Command{
 	Use:   "create [workspace] [repo-slug]",
 	Short: "Create a new pull request",
-	Args:  cobra.RangeArgs(0, 2),
+	Long: `Creates a pull request from --source (default: the branch checked out) onto
+--destination (default: the repository's main branch).
+
+Without --title, the title comes from the branch: the summary of its only
+commit, or else the branch name. Without --description, the description starts
+from the repository's pull request template on the destination branch
+(.bitbucket/PULL_REQUEST_TEMPLATE.md and the like), then from --template or
+~/.config/bbkt/pull_request_template.md. The repository's default reviewers are
+added alongside any --reviewer.
+
+When run in a terminal without --title and --description, or with --edit, the
+title and description open in $VISUAL or $EDITOR for a final edit.`,
+	Args: cobra.RangeArgs(0, 2),
 	Run: func(cmd *cobra.Command, args []string) {
 		workspace, repoSlug, _, err := ParseArgs(args, 0)
 		if err != nil {
@@ -154,23 +170,29 @@ var prsCreateCmd = &cobra.Command{
 		desc, _ := cmd.Flags().GetString("description")
 		closeSource, _ := cmd.Flags().GetBool("close-source-branch")
 		draft, _ := cmd.Flags().GetBool("draft")
+		reviewerNames, _ := cmd.Flags().GetStringSlice("reviewer")
+		templatePath, _ := cmd.Flags().GetString("template")
+		noTemplate, _ := cmd.Flags().GetBool("no-template")
+		noDefaultReviewers, _ := cmd.Flags().GetBool("no-default-reviewers")
+		edit, _ := cmd.Flags().GetBool("edit")
+		if !cmd.Flags().Changed("edit") {
+			edit = title == "" && desc == "" && isTerminal()
+		}
+
+		if source == "" {
+			source, _ = bitbucket.GetCurrentBranch()
+		}
 
 		interactive := false
-		if title == "" || source == "" {
+		if source == "" {
 			interactive = true
 			fmt.Println("Missing required arguments. Entering interactive mode...")
 
 			form := huh.NewForm(
 				huh.NewGroup(
 					huh.NewInput().
-						Title("Title").
-						Value(&title).
-						Validate(func(s string) error {
-							if strings.TrimSpace(s) == "" {
-								return fmt.Errorf("title is required")
-							}
-							return nil
-						}),
+						Title("Title (optional, defaults from the branch's commits)").
+						Value(&title),
 					huh.NewInput().
 						Title("Source Branch").
 						Value(&source).
@@ -199,18 +221,73 @@ var prsCreateCmd = &cobra.Command{
 				fmt.Fprintln(os.Stderr, "Draft PR creation cancelled.")
 				os.Exit(1)
 			}
+			edit = false
 		}
 
-		if title == "" || source == "" {
-			fmt.Fprintln(os.Stderr, "Error: title and source branch are required")
+		if source == "" {
+			fmt.Fprintln(os.Stderr, "Error: source branch is required")
 			os.Exit(1)
 		}
 
-		if interactive {
+		client := getClient()
+		if dest == "" && (title == "" || (desc == "" && !noTemplate)) {
+			repo, err := client.GetRepository(bitbucket.GetRepositoryArgs{Workspace: workspace, RepoSlug: repoSlug})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to resolve destination branch: %v\n", err)
+				os.Exit(1)
+			}
+			if repo.MainBranch != nil {
+				dest = repo.MainBranch.Name
+			}
+		}
+
+		if title == "" {
+			commits, err := client.ListCommits(bitbucket.ListCommitsArgs{
+				Workspace: workspace,
+				RepoSlug:  repoSlug,
+				Include:   source,
+				Exclude:   dest,
+				Pagelen:   2,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to list commits of %s: %v\n", source, err)
+				os.Exit(1)
+			}
+			title = bitbucket.DefaultPRTitle(source, commits.Values)
+		}
+
+		if desc == "" && !noTemplate {
+			desc, err = prTemplate(client, workspace, repoSlug, dest, templatePath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		if edit {
+			title, desc, err = editTitleAndBody(title, desc)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if title == "" {
+				fmt.Fprintln(os.Stderr, "Empty title, pull request creation cancelled.")
+				os.Exit(1)
+			}
+		}
+
+		if interactive || edit {
 			fmt.Println("Creating pull request...")
 		}
 
-		client := getClient()
+		reviewers, err := client.ResolveReviewers(workspace, reviewerNames)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		if !noDefaultReviewers {
+			reviewers = addDefaultReviewers(client, workspace, repoSlug, reviewers)
+		}
 		result, err := client.CreatePullRequest(bitbucket.CreatePullRequestArgs{
 			Workspace:         workspace,
 			RepoSlug:          repoSlug,
@@ -220,6 +297,7 @@ var prsCreateCmd = &cobra.Command{
 			Description:       desc,
 			CloseSourceBranch: closeSource,
 			Draft:             draft,
+			Reviewers:         reviewers,
 		})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -238,15 +316,92 @@ var prsCreateCmd = &cobra.Command{
 			if result.Draft {
 				KV("Draft", "yes")
 			}
+			if len(result.Reviewers) > 0 {
+				names := make([]string, len(result.Reviewers))
+				for i, r := range result.Reviewers {
+					names[i] = r.DisplayName
+				}
+				KV("Reviewers", strings.Join(names, ", "))
+			}
 			KV("Created", FormatTime(result.CreatedOn))
 		})
 	},
 }
 
+// prTemplate returns the description template for a new pull request: the
+// repository's on dest, else the file at path, else the user's local one.
+func prTemplate(client *bitbucket.Client, workspace, repoSlug, dest, path string) (string, error) {
+	template, err := client.GetPullRequestTemplate(workspace, repoSlug, dest)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pull request template: %v", err)
+	}
+	if template != "" {
+		return template, nil
+	}
+
+	if path == "" {
+		local, err := bitbucket.LocalPullRequestTemplatePath()
+		if err != nil {
+			return "", nil
+		}
+		if _, err := os.Stat(local); err != nil {
+			return "", nil
+		}
+		path = local
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %v", err)
+	}
+	return string(data), nil
+}
+
+// addDefaultReviewers adds the repository's default reviewers to reviewers,
+// leaving out the pull request's author, whom Bitbucket refuses as a reviewer.
+// Failing to read them, or to look up the author, is reported but doesn't
+// stop the pull request.
+func addDefaultReviewers(client *bitbucket.Client, workspace, repoSlug string, reviewers []bitbucket.ReviewerRef) []bitbucket.ReviewerRef {
+	defaults, err := client.ListDefaultReviewers(workspace, repoSlug)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not read default reviewers: %v\n", err)
+		return reviewers
+	}
+	if len(defaults) == 0 {
+		return reviewers
+	}
+
+	me, err := client.GetCurrentUser()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not look up the current user to leave out of the default reviewers: %v\n", err)
+		return reviewers
+	}
+	for _, u := range defaults {
+		if u.UUID == me.UUID {
+			continue
+		}
+		if slices.ContainsFunc(reviewers, func(r bitbucket.ReviewerRef) bool { return r.Matches(u) }) {
+			continue
+		}
+		reviewers = append(reviewers, bitbucket.ReviewerRef{UUID: u.UUID})
+	}
+	return reviewers
+}
+
 var prsMergeCmd = &cobra.Command{
 	Use:   "merge [workspace] [repo-slug] [pr-id]",
 	Short: "Merge a pull request",
-	Args:  cobra.RangeArgs(1, 3),
+	Long: `Checks approvals, change requests, builds and open tasks, then merges the
+pull request and waits for Bitbucket to finish.
+
+The strategy must be one the destination branch allows and defaults to the
+branch's default. Checks enforced by the destination's branch restrictions
+block the merge; the rest are reported as warnings. --check runs the checks
+without merging.
+
+--when-ready waits until the enforced checks pass and then merges, reporting
+progress on stderr. It gives up without merging when new commits are pushed,
+a required build fails or --timeout expires.`,
+	Args: cobra.RangeArgs(1, 3),
 	Run: func(cmd *cobra.Command, args []string) {
 		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
 		if err != nil {
@@ -263,32 +418,91 @@ var prsMergeCmd = &cobra.Command{
 		strategy, _ := cmd.Flags().GetString("strategy")
 		msg, _ := cmd.Flags().GetString("message")
 		closeSource, _ := cmd.Flags().GetBool("close-source-branch")
+		checkOnly, _ := cmd.Flags().GetBool("check")
+		whenReady, _ := cmd.Flags().GetBool("when-ready")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		interval, _ := cmd.Flags().GetDuration("interval")
 
-		client := getClient()
-		result, err := client.MergePullRequest(bitbucket.MergePullRequestArgs{
+		mergeArgs := bitbucket.MergePullRequestArgs{
 			Workspace:         workspace,
 			RepoSlug:          repoSlug,
 			PRID:              prID,
 			MergeStrategy:     strategy,
 			Message:           msg,
 			CloseSourceBranch: closeSource,
-		})
+			CheckOnly:         checkOnly,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		client := getClient()
+		var result *bitbucket.MergeResult
+		if whenReady && !checkOnly {
+			last := ""
+			result, err = client.MergeWhenReady(ctx, bitbucket.MergeWhenReadyArgs{
+				MergePullRequestArgs: mergeArgs,
+				Timeout:              timeout,
+				Interval:             interval,
+				OnPoll: func(r *bitbucket.MergeResult) {
+					if summary := r.Summary(); summary != last {
+						fmt.Fprintf(os.Stderr, "[%s] PR #%d: %s\n", time.Now().Format("15:04:05"), prID, summary)
+						last = summary
+					}
+				},
+			})
+		} else {
+			result, err = client.MergePullRequest(ctx, mergeArgs)
+		}
 		if err != nil {
+			if result != nil && !outputJSON(cmd) {
+				printMergeChecks(result)
+				fmt.Println()
+			}
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 
 		PrintOrJSON(cmd, result, func() {
-			fmt.Printf("Merged Pull Request #%d: %s\n", result.ID, result.Title)
-			KV("State", result.State)
-			if result.MergeCommit != nil {
-				KV("Merge Commit", result.MergeCommit.Hash[:12])
+			printMergeChecks(result)
+			fmt.Println()
+			pr := result.PullRequest
+			if !result.Merged {
+				fmt.Printf("Pull Request #%d is ready to merge with %s.\n", pr.ID, result.Strategy)
+				return
 			}
-			KV("Updated", FormatTime(result.UpdatedOn))
+			fmt.Printf("Merged Pull Request #%d: %s\n", pr.ID, pr.Title)
+			KV("Strategy", result.Strategy)
+			KV("State", pr.State)
+			if pr.MergeCommit != nil {
+				KV("Merge Commit", Truncate(pr.MergeCommit.Hash, 12))
+			}
+			KV("Updated", FormatTime(pr.UpdatedOn))
 		})
 	},
 }
 
+// printMergeChecks prints the outcome of each merge check.
+func printMergeChecks(result *bitbucket.MergeResult) {
+	t := NewTable()
+	t.Header("Check", "Status", "Enforced", "Detail")
+	for _, c := range result.Checks {
+		status := "pass"
+		if !c.Passed {
+			status = "FAIL"
+		}
+		enforced := "advisory"
+		if c.Required {
+			enforced = "required"
+		}
+		t.Row(c.Name, status, enforced, c.Detail)
+	}
+	t.Flush()
+	if result.Note != "" {
+		fmt.Printf("Note: %s\n", result.Note)
+	}
+}
+
 var prsApproveCmd = &cobra.Command{
 	Use:   "approve [workspace] [repo-slug] [pr-id]",
 	Short: "Approve a pull request",
@@ -365,14 +579,23 @@ func init() {
 	prsListCmd.Flags().StringP("query", "q", "", "Filter pull requests using Bitbucket query syntax")
 	prsListCmd.Flags().String("state", "OPEN", "Filter by state (MERGED, SUPERSEDED, OPEN, DECLINED)")
 
-	prsCreateCmd.Flags().StringP("title", "t", "", "Title of the pull request (required)")
-	prsCreateCmd.Flags().StringP("source", "s", "", "Source branch name (required)")
+	prsCreateCmd.Flags().StringP("title", "t", "", "Title of the pull request (default: from the branch's commits)")
+	prsCreateCmd.Flags().StringP("source", "s", "", "Source branch name (default: the current branch)")
 	prsCreateCmd.Flags().StringP("destination", "d", "", "Destination branch name (optional, defaults to repo default)")
 	prsCreateCmd.Flags().String("description", "", "Description of the pull request")
 	prsCreateCmd.Flags().Bool("close-source-branch", true, "Close source branch on merge")
 	prsCreateCmd.Flags().Bool("draft", false, "Create as a draft PR")
-
-	prsMergeCmd.Flags().String("strategy", "merge_commit", "Merge strategy (merge_commit, squash, fast_forward)")
+	prsCreateCmd.Flags().StringSlice("reviewer", nil, "Reviewers to add (account ID, {UUID} or nickname; repeatable)")
+	prsCreateCmd.Flags().Bool("no-default-reviewers", false, "Don't add the repository's default reviewers")
+	prsCreateCmd.Flags().String("template", "", "Description template file, used when the repository has none")
+	prsCreateCmd.Flags().Bool("no-template", false, "Don't start the description from a template")
+	prsCreateCmd.Flags().BoolP("edit", "e", false, "Edit the title and description in $EDITOR (default: when run in a terminal without --title or --description)")
+
+	prsMergeCmd.Flags().String("strategy", "", "Merge strategy (merge_commit, squash, fast_forward, squash_fast_forward, rebase_fast_forward, rebase_merge; default: the branch's default)")
+	prsMergeCmd.Flags().Bool("check", false, "Run the merge checks without merging")
+	prsMergeCmd.Flags().Bool("when-ready", false, "Wait until the enforced checks pass, then merge")
+	prsMergeCmd.Flags().Duration("timeout", bitbucket.DefaultMergeWhenReadyTimeout, "How long --when-ready waits for the checks")
+	prsMergeCmd.Flags().Duration("interval", bitbucket.DefaultMergeWhenReadyInterval, "How often --when-ready re-checks")
 	prsMergeCmd.Flags().StringP("message", "m", "", "Commit message")
 	prsMergeCmd.Flags().Bool("close-source-branch", true, "Close source branch")
 }
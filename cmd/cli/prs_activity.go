@@ -0,0 +1,64 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+var prsActivityCmd = &cobra.Command{
+	Use:   "activity [workspace] [repo-slug] [pr-id]",
+	Short: "Show the history of a pull request",
+	Long: `Shows a pull request's updates, reviews, reviewer changes, comments and
+merges in chronological order.`,
+	Args: cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		prID, err := strconv.Atoi(trailing[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid PR ID: %s\n", trailing[0])
+			os.Exit(1)
+		}
+
+		client := getClient()
+		result, err := client.GetPRActivity(bitbucket.PullRequestActionArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			PRID:      prID,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		events := bitbucket.PRTimeline(result.Values)
+		PrintOrJSON(cmd, events, func() {
+			if len(events) == 0 {
+				fmt.Println("No activity found.")
+				return
+			}
+			t := NewTable()
+			t.Header("When", "Who", "What", "Detail")
+			for _, e := range events {
+				who := "-"
+				if e.Actor != nil {
+					who = e.Actor.DisplayName
+				}
+				t.Row(FormatTimeAgo(e.Date), who, e.Action, Truncate(e.Detail, 60))
+			}
+			t.Flush()
+		})
+	},
+}
+
+func init() {
+	prsCmd.AddCommand(prsActivityCmd)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/zach-snell/bbkt/internal/bitbucket"
@@ -73,9 +74,16 @@ var prCommentsListCmd = &cobra.Command{
 }
 
 var prCommentsAddCmd = &cobra.Command{
-	Use:   "add [workspace] [repo-slug] [pr-id]",
-	Short: "Add a comment to a pull request",
-	Args:  cobra.RangeArgs(1, 3),
+	Use:     "add [workspace] [repo-slug] [pr-id]",
+	Aliases: []string{"create"},
+	Short:   "Add a comment to a pull request",
+	Long: `Adds a general, reply or inline comment to a pull request.
+
+Inline comments (--file with --line, --to or --from) are checked against the
+pull request's diff. --line takes a line or a range such as 12-18, on the new
+side unless --side old. Lines outside the diff are rejected with the ranges
+that can be commented on, or moved to the nearest hunk with --snap.`,
+	Args: cobra.RangeArgs(1, 3),
 	Run: func(cmd *cobra.Command, args []string) {
 		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
 		if err != nil {
@@ -99,18 +107,53 @@ var prCommentsAddCmd = &cobra.Command{
 		file, _ := cmd.Flags().GetString("file")
 		toCode, _ := cmd.Flags().GetInt("to")
 		fromCode, _ := cmd.Flags().GetInt("from")
+		lines, _ := cmd.Flags().GetString("line")
+		side, _ := cmd.Flags().GetString("side")
+		snap, _ := cmd.Flags().GetBool("snap")
+
+		target := bitbucket.InlineTarget{Path: file, Side: side, Snap: snap}
+		switch {
+		case lines != "":
+			if target.Line, target.EndLine, err = parseLineRange(lines); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		case toCode > 0:
+			target.Line, target.Side = toCode, bitbucket.DiffSideNew
+		case fromCode > 0:
+			target.Line, target.Side = fromCode, bitbucket.DiffSideOld
+		}
+		if target.Line > 0 && file == "" {
+			fmt.Fprintln(os.Stderr, "Error: --file is required for inline comments")
+			os.Exit(1)
+		}
 
 		client := getClient()
-		result, err := client.CreatePRComment(bitbucket.CreatePRCommentArgs{
+		create := bitbucket.CreatePRCommentArgs{
 			Workspace: workspace,
 			RepoSlug:  repoSlug,
 			PRID:      prID,
 			Content:   content,
 			ParentID:  parentID,
 			FilePath:  file,
-			LineTo:    toCode,
-			LineFrom:  fromCode,
-		})
+		}
+		if target.Line > 0 {
+			anchor, err := client.AnchorPRComment(bitbucket.PullRequestActionArgs{
+				Workspace: workspace,
+				RepoSlug:  repoSlug,
+				PRID:      prID,
+			}, target)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			if anchor.Note != "" {
+				fmt.Fprintf(os.Stderr, "Note: %s\n", anchor.Note)
+			}
+			anchor.Apply(&create)
+		}
+
+		result, err := client.CreatePRComment(create)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -124,6 +167,9 @@ var prCommentsAddCmd = &cobra.Command{
 			KV("Content", Truncate(result.Content.Raw, 80))
 			if result.Inline != nil {
 				KV("File", result.Inline.Path)
+				if line := inlineLines(result.Inline); line != "" {
+					KV("Line", line)
+				}
 			}
 			KV("Created", FormatTime(result.CreatedOn))
 		})
@@ -169,6 +215,37 @@ var prCommentsResolveCmd = &cobra.Command{
 	},
 }
 
+// parseLineRange parses "12" or "12-18".
+func parseLineRange(s string) (start, end int, err error) {
+	first, last, isRange := strings.Cut(s, "-")
+	if start, err = strconv.Atoi(first); err != nil || start <= 0 {
+		return 0, 0, fmt.Errorf("invalid line: %s", s)
+	}
+	if !isRange {
+		return start, 0, nil
+	}
+	if end, err = strconv.Atoi(last); err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid line range: %s", s)
+	}
+	return start, end, nil
+}
+
+// inlineLines describes the lines an inline comment covers, e.g. "12-18" or "old 7".
+func inlineLines(in *bitbucket.Inline) string {
+	start, end, side := in.StartTo, in.To, ""
+	if end == nil {
+		start, end, side = in.StartFrom, in.From, "old "
+	}
+	switch {
+	case end == nil:
+		return ""
+	case start == nil || *start == *end:
+		return fmt.Sprintf("%s%d", side, *end)
+	default:
+		return fmt.Sprintf("%s%d-%d", side, *start, *end)
+	}
+}
+
 func init() {
 	prsCmd.AddCommand(prCommentsCmd)
 	prCommentsCmd.AddCommand(prCommentsListCmd)
@@ -180,4 +257,7 @@ func init() {
 	prCommentsAddCmd.Flags().String("file", "", "File path for inline comments")
 	prCommentsAddCmd.Flags().Int("to", 0, "Line number the comment applies to (for new or modified lines)")
 	prCommentsAddCmd.Flags().Int("from", 0, "Line number the comment applies to (for deleted lines)")
+	prCommentsAddCmd.Flags().String("line", "", "Line or range (e.g. 12-18) to comment on inline")
+	prCommentsAddCmd.Flags().String("side", "new", "Diff side --line refers to: new, or old for deleted lines")
+	prCommentsAddCmd.Flags().Bool("snap", false, "Move lines outside the diff to the nearest hunk instead of failing")
 }
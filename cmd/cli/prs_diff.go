@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+var prsDiffCmd = &cobra.Command{
+	Use:   "diff [workspace] [repo-slug] [pr-id]",
+	Short: "Show the diff of a pull request",
+	Long: `Prints the unified diff of a pull request. --path limits it to files, directories
+or globs and can be repeated:
+
+  bbkt prs diff 42 --path 'src/api/*.go' --path docs/ --context 10
+
+--stat prints the per-file line counts instead, and --json prints each file's
+diff as a separate chunk.`,
+	Args: cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		prID, err := strconv.Atoi(trailing[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid PR ID: %s\n", trailing[0])
+			os.Exit(1)
+		}
+
+		paths, _ := cmd.Flags().GetStringSlice("path")
+		contextLines, _ := cmd.Flags().GetInt("context")
+		ignoreWhitespace, _ := cmd.Flags().GetBool("ignore-whitespace")
+		noBinary, _ := cmd.Flags().GetBool("no-binary")
+		stat, _ := cmd.Flags().GetBool("stat")
+
+		opts := bitbucket.DiffOptions{
+			IgnoreWhitespace: ignoreWhitespace,
+			ExcludeBinary:    noBinary,
+			Paths:            paths,
+		}
+		if contextLines >= 0 {
+			opts.Context = &contextLines
+		}
+
+		client := getClient()
+		raw, err := client.GetPRDiff(bitbucket.PullRequestActionArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			PRID:      prID,
+		}, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		files := bitbucket.ParseDiff(raw)
+		PrintOrJSON(cmd, files, func() {
+			if len(files) == 0 {
+				fmt.Println("No changes.")
+				return
+			}
+			if !stat {
+				_, _ = os.Stdout.Write(raw)
+				return
+			}
+			t := NewTable()
+			t.Header("Status", "File", "Lines")
+			added, removed := 0, 0
+			for _, f := range files {
+				path := f.Path()
+				if f.Status == "renamed" {
+					path = f.OldPath + " → " + f.NewPath
+				}
+				lines := fmt.Sprintf("+%d -%d", f.LinesAdded, f.LinesRemoved)
+				if f.Binary {
+					lines = "binary"
+				}
+				t.Row(f.Status, path, lines)
+				added += f.LinesAdded
+				removed += f.LinesRemoved
+			}
+			t.Flush()
+			fmt.Printf("\n%d files, +%d -%d\n", len(files), added, removed)
+		})
+	},
+}
+
+func init() {
+	prsCmd.AddCommand(prsDiffCmd)
+
+	prsDiffCmd.Flags().StringSlice("path", nil, "Only show files matching this path, directory or glob (repeatable)")
+	prsDiffCmd.Flags().IntP("context", "U", -1, "Lines of context around each change (default: Bitbucket's, 3)")
+	prsDiffCmd.Flags().BoolP("ignore-whitespace", "w", false, "Ignore whitespace-only changes")
+	prsDiffCmd.Flags().Bool("no-binary", false, "Leave binary files out")
+	prsDiffCmd.Flags().Bool("stat", false, "Show per-file line counts instead of the diff")
+}
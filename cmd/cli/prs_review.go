@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// reviewVerdicts maps 'prs review' verdicts to the client call and a past-tense message.
+var reviewVerdicts = map[string]struct {
+	do   func(c *bitbucket.Client, args bitbucket.PullRequestActionArgs) error
+	done string
+}{
+	"approve":                {(*bitbucket.Client).ApprovePullRequest, "approved"},
+	"unapprove":              {(*bitbucket.Client).UnapprovePullRequest, "unapproved"},
+	"request-changes":        {(*bitbucket.Client).RequestChangesPullRequest, "marked as needing changes"},
+	"remove-request-changes": {(*bitbucket.Client).RemoveRequestChangesPullRequest, "no longer marked as needing changes"},
+}
+
+var prsReviewCmd = &cobra.Command{
+	Use:   "review [workspace] [repo-slug] [pr-id] [approve|unapprove|request-changes|remove-request-changes]",
+	Short: "Approve or request changes on a pull request",
+	Args:  cobra.RangeArgs(2, 4),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug, trailing, err := ParseArgs(args, 2)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		prID, err := strconv.Atoi(trailing[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid PR ID: %s\n", trailing[0])
+			os.Exit(1)
+		}
+		verdict, ok := reviewVerdicts[trailing[1]]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Invalid review: %s (expected approve, unapprove, request-changes or remove-request-changes)\n", trailing[1])
+			os.Exit(1)
+		}
+
+		client := getClient()
+		err = verdict.do(client, bitbucket.PullRequestActionArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			PRID:      prID,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Pull request #%d %s.\n", prID, verdict.done)
+	},
+}
+
+var prsReviewersCmd = &cobra.Command{
+	Use:   "reviewers [workspace] [repo-slug] [pr-id]",
+	Short: "List, add or remove pull request reviewers",
+	Long: `Lists the reviewers of a pull request and their review state.
+--add and --remove take account IDs, {UUID}s or workspace member nicknames.`,
+	Args: cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		prID, err := strconv.Atoi(trailing[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid PR ID: %s\n", trailing[0])
+			os.Exit(1)
+		}
+
+		add, _ := cmd.Flags().GetStringSlice("add")
+		remove, _ := cmd.Flags().GetStringSlice("remove")
+
+		client := getClient()
+		pr, err := client.GetPullRequest(bitbucket.GetPullRequestArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			PRID:      prID,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(add) > 0 || len(remove) > 0 {
+			addRefs, err := client.ResolveReviewers(workspace, add)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			removeRefs, err := client.ResolveReviewers(workspace, remove)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			reviewers := bitbucket.ChangeReviewers(pr.Reviewers, addRefs, removeRefs)
+			pr, err = client.UpdatePullRequest(bitbucket.UpdatePullRequestArgs{
+				Workspace: workspace,
+				RepoSlug:  repoSlug,
+				PRID:      prID,
+				Reviewers: &reviewers,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		PrintOrJSON(cmd, pr.Reviewers, func() {
+			if len(pr.Reviewers) == 0 {
+				fmt.Printf("Pull request #%d has no reviewers.\n", prID)
+				return
+			}
+			t := NewTable()
+			t.Header("Reviewer", "Nickname", "Account ID", "State")
+			for _, r := range pr.Reviewers {
+				t.Row(r.DisplayName, r.Nickname, r.AccountID, reviewState(pr, r))
+			}
+			t.Flush()
+		})
+	},
+}
+
+// reviewState returns the review state of reviewer u on pr.
+func reviewState(pr *bitbucket.PullRequest, u bitbucket.User) string {
+	for _, p := range pr.Participants {
+		if p.User == nil || p.User.UUID != u.UUID {
+			continue
+		}
+		switch {
+		case p.Approved:
+			return "approved"
+		case p.State == "changes_requested":
+			return "changes requested"
+		}
+	}
+	return "pending"
+}
+
+func init() {
+	prsCmd.AddCommand(prsReviewCmd)
+	prsCmd.AddCommand(prsReviewersCmd)
+
+	prsReviewersCmd.Flags().StringSlice("add", nil, "Reviewers to add (account ID, {UUID} or nickname)")
+	prsReviewersCmd.Flags().StringSlice("remove", nil, "Reviewers to remove (account ID, {UUID} or nickname)")
+}
@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+var prTasksCmd = &cobra.Command{
+	Use:     "tasks",
+	Aliases: []string{"task"},
+	Short:   "Manage pull request tasks",
+}
+
+// parseTaskArgs resolves [workspace] [repo-slug] pr-id task-id.
+func parseTaskArgs(args []string) bitbucket.PRTaskArgs {
+	workspace, repoSlug, trailing, err := ParseArgs(args, 2)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	prID, err := strconv.Atoi(trailing[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid PR ID: %s\n", trailing[0])
+		os.Exit(1)
+	}
+	taskID, err := strconv.Atoi(trailing[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid task ID: %s\n", trailing[1])
+		os.Exit(1)
+	}
+
+	return bitbucket.PRTaskArgs{Workspace: workspace, RepoSlug: repoSlug, PRID: prID, TaskID: taskID}
+}
+
+func printTask(cmd *cobra.Command, verb string, task *bitbucket.PRTask) {
+	PrintOrJSON(cmd, task, func() {
+		fmt.Printf("%s task #%d\n", verb, task.ID)
+		KV("State", task.State)
+		KV("Content", Truncate(task.Content.Raw, 80))
+		if task.Comment != nil && task.Comment.ID != 0 {
+			KVf("Comment", "%d", task.Comment.ID)
+		}
+	})
+}
+
+var prTasksListCmd = &cobra.Command{
+	Use:   "list [workspace] [repo-slug] [pr-id]",
+	Short: "List tasks on a pull request",
+	Args:  cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		prID, err := strconv.Atoi(trailing[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid PR ID: %s\n", trailing[0])
+			os.Exit(1)
+		}
+
+		state, _ := cmd.Flags().GetString("state")
+
+		client := getClient()
+		result, err := client.ListPRTasks(bitbucket.ListPRTasksArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			PRID:      prID,
+			State:     strings.ToUpper(state),
+			All:       true,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		PrintOrJSON(cmd, result, func() {
+			if len(result.Values) == 0 {
+				fmt.Println("No tasks found.")
+				return
+			}
+			t := NewTable()
+			t.Header("ID", "State", "Content", "Creator", "Comment", "Created")
+			for _, task := range result.Values {
+				creator := "-"
+				if task.Creator != nil {
+					creator = task.Creator.DisplayName
+				}
+				comment := "-"
+				if task.Comment != nil && task.Comment.ID != 0 {
+					comment = strconv.Itoa(task.Comment.ID)
+				}
+				t.Row(
+					strconv.Itoa(task.ID),
+					task.State,
+					Truncate(task.Content.Raw, 50),
+					creator,
+					comment,
+					FormatTime(task.CreatedOn),
+				)
+			}
+			t.Flush()
+		})
+	},
+}
+
+var prTasksAddCmd = &cobra.Command{
+	Use:   "add [workspace] [repo-slug] [pr-id]",
+	Short: "Add a task to a pull request",
+	Args:  cobra.RangeArgs(1, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug, trailing, err := ParseArgs(args, 1)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		prID, err := strconv.Atoi(trailing[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid PR ID: %s\n", trailing[0])
+			os.Exit(1)
+		}
+
+		content, _ := cmd.Flags().GetString("content")
+		if content == "" {
+			fmt.Fprintln(os.Stderr, "Error: content is required")
+			os.Exit(1)
+		}
+		commentID, _ := cmd.Flags().GetInt("comment")
+
+		client := getClient()
+		result, err := client.CreatePRTask(bitbucket.CreatePRTaskArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			PRID:      prID,
+			Content:   content,
+			CommentID: commentID,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printTask(cmd, "Added", result)
+	},
+}
+
+var prTasksUpdateCmd = &cobra.Command{
+	Use:   "update [workspace] [repo-slug] [pr-id] [task-id]",
+	Short: "Edit a pull request task",
+	Args:  cobra.RangeArgs(2, 4),
+	Run: func(cmd *cobra.Command, args []string) {
+		task := parseTaskArgs(args)
+
+		content, _ := cmd.Flags().GetString("content")
+		if content == "" {
+			fmt.Fprintln(os.Stderr, "Error: content is required")
+			os.Exit(1)
+		}
+
+		client := getClient()
+		result, err := client.UpdatePRTask(bitbucket.UpdatePRTaskArgs{
+			Workspace: task.Workspace,
+			RepoSlug:  task.RepoSlug,
+			PRID:      task.PRID,
+			TaskID:    task.TaskID,
+			Content:   content,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		printTask(cmd, "Updated", result)
+	},
+}
+
+// taskStateCmd builds the resolve and reopen commands, which only differ in
+// the state they set.
+func taskStateCmd(use, short, state, verb string) *cobra.Command {
+	return &cobra.Command{
+		Use:   use + " [workspace] [repo-slug] [pr-id] [task-id]",
+		Short: short,
+		Args:  cobra.RangeArgs(2, 4),
+		Run: func(cmd *cobra.Command, args []string) {
+			task := parseTaskArgs(args)
+
+			client := getClient()
+			result, err := client.UpdatePRTask(bitbucket.UpdatePRTaskArgs{
+				Workspace: task.Workspace,
+				RepoSlug:  task.RepoSlug,
+				PRID:      task.PRID,
+				TaskID:    task.TaskID,
+				State:     state,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+
+			printTask(cmd, verb, result)
+		},
+	}
+}
+
+var (
+	prTasksResolveCmd = taskStateCmd("resolve", "Mark a pull request task as done", bitbucket.TaskStateResolved, "Resolved")
+	prTasksReopenCmd  = taskStateCmd("reopen", "Reopen a resolved pull request task", bitbucket.TaskStateUnresolved, "Reopened")
+)
+
+var prTasksDeleteCmd = &cobra.Command{
+	Use:   "delete [workspace] [repo-slug] [pr-id] [task-id]",
+	Short: "Delete a pull request task",
+	Args:  cobra.RangeArgs(2, 4),
+	Run: func(cmd *cobra.Command, args []string) {
+		task := parseTaskArgs(args)
+
+		client := getClient()
+		if err := client.DeletePRTask(task); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Task #%d deleted successfully.\n", task.TaskID)
+	},
+}
+
+func init() {
+	prsCmd.AddCommand(prTasksCmd)
+	prTasksCmd.AddCommand(prTasksListCmd)
+	prTasksCmd.AddCommand(prTasksAddCmd)
+	prTasksCmd.AddCommand(prTasksUpdateCmd)
+	prTasksCmd.AddCommand(prTasksResolveCmd)
+	prTasksCmd.AddCommand(prTasksReopenCmd)
+	prTasksCmd.AddCommand(prTasksDeleteCmd)
+
+	prTasksListCmd.Flags().String("state", "", "Filter by state (RESOLVED, UNRESOLVED)")
+	prTasksAddCmd.Flags().StringP("content", "m", "", "Task description")
+	prTasksAddCmd.Flags().Int("comment", 0, "Comment ID to anchor the task to")
+	prTasksUpdateCmd.Flags().StringP("content", "m", "", "New task description")
+}
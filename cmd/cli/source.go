@@ -230,7 +230,7 @@ var sourceWriteCmd = &cobra.Command{
 		author, _ := cmd.Flags().GetString("author")
 
 		client := getClient()
-		err = client.WriteFile(bitbucket.WriteFileArgs{
+		commit, err := client.WriteFile(bitbucket.WriteFileArgs{
 			Workspace: workspace,
 			RepoSlug:  repoSlug,
 			Path:      trailing[0],
@@ -244,6 +244,10 @@ var sourceWriteCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if commit != nil {
+			fmt.Printf("Successfully wrote file '%s' in commit %s\n", trailing[0], commit.Hash)
+			return
+		}
 		fmt.Printf("Successfully wrote file '%s'\n", trailing[0])
 	},
 }
@@ -264,7 +268,7 @@ var sourceDeleteCmd = &cobra.Command{
 		author, _ := cmd.Flags().GetString("author")
 
 		client := getClient()
-		err = client.DeleteFile(bitbucket.DeleteFileArgs{
+		commit, err := client.DeleteFile(bitbucket.DeleteFileArgs{
 			Workspace: workspace,
 			RepoSlug:  repoSlug,
 			Path:      trailing[0],
@@ -277,6 +281,10 @@ var sourceDeleteCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		if commit != nil {
+			fmt.Printf("Successfully deleted file '%s' in commit %s\n", trailing[0], commit.Hash)
+			return
+		}
 		fmt.Printf("Successfully deleted file '%s'\n", trailing[0])
 	},
 }
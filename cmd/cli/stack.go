@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+var stackCmd = &cobra.Command{
+	Use:   "stack",
+	Short: "Work with stacks of dependent pull requests",
+	Long: `A stack is a chain of branches where each pull request targets the branch
+below it. bbkt reads stacks back from the open pull requests, so there is
+nothing to set up beyond the branches themselves.
+
+Stacked pull requests are created without closing their source branch on
+merge: Bitbucket declines pull requests whose destination branch is deleted,
+so keep the bottom branch until 'bbkt stack sync' has retargeted the next one.`,
+}
+
+var stackCreateCmd = &cobra.Command{
+	Use:   "create <branch>...",
+	Short: "Open a chain of pull requests, each onto the previous branch",
+	Long: `Opens a pull request for each branch onto the one before it, the first onto
+--base (default: the repository's main branch). Branches that already have an
+open pull request keep it, retargeted if needed, so the command can be rerun
+after adding a branch on top:
+
+  bbkt stack create feature/api feature/client feature/ui`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug := stackRepo(cmd)
+		base, _ := cmd.Flags().GetString("base")
+		draft, _ := cmd.Flags().GetBool("draft")
+
+		client := getClient()
+		stack, err := client.CreateStack(bitbucket.CreateStackArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			Branches:  args,
+			Base:      base,
+			Draft:     draft,
+		})
+		if err != nil {
+			if stack != nil && len(stack.PullRequests) > 0 {
+				printStack(stack, "")
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		PrintOrJSON(cmd, stack, func() {
+			printStack(stack, "")
+		})
+	},
+}
+
+var stackShowCmd = &cobra.Command{
+	Use:     "show [branch]",
+	Aliases: []string{"status", "list"},
+	Short:   "Show the stack a branch belongs to",
+	Long:    `Shows each pull request of the stack, bottom first, with its state and reviews. The branch defaults to the one checked out.`,
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug := stackRepo(cmd)
+		branch := stackBranch(args)
+
+		client := getClient()
+		stack, err := client.GetStack(bitbucket.StackArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			Branch:    branch,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Pull request listings leave out participants; fetch each for its reviews.
+		for i, pr := range stack.PullRequests {
+			full, err := client.GetPullRequest(bitbucket.GetPullRequestArgs{
+				Workspace: workspace,
+				RepoSlug:  repoSlug,
+				PRID:      pr.ID,
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			stack.PullRequests[i] = *full
+		}
+
+		PrintOrJSON(cmd, stack, func() {
+			printStack(stack, branch)
+		})
+	},
+}
+
+var stackSyncCmd = &cobra.Command{
+	Use:   "sync [branch]",
+	Short: "Retarget the stack after its bottom pull request merged",
+	Long: `Once the pull request at the bottom of a stack has merged, retargets the next
+one onto the branch it merged into and prints the git commands that rebase the
+remaining branches. --comment also posts those commands on the retargeted
+pull request. The branch defaults to the one checked out.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		workspace, repoSlug := stackRepo(cmd)
+		branch := stackBranch(args)
+		comment, _ := cmd.Flags().GetBool("comment")
+
+		client := getClient()
+		stack, retargets, err := client.SyncStack(bitbucket.StackArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			Branch:    branch,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		notes := bitbucket.RebaseNotes(stack, retargets)
+		if comment && notes != "" {
+			last := retargets[len(retargets)-1]
+			_, err := client.CreatePRComment(bitbucket.CreatePRCommentArgs{
+				Workspace: workspace,
+				RepoSlug:  repoSlug,
+				PRID:      last.PullRequest.ID,
+				Content: fmt.Sprintf("Retargeted onto `%s` after #%d merged. To rebase the stack:\n\n```\n%s```",
+					last.To, last.Merged.ID, notes),
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to post rebase notes: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		PrintOrJSON(cmd, map[string]any{"stack": stack, "retargets": retargets, "rebase": notes}, func() {
+			if len(retargets) == 0 {
+				fmt.Println("Nothing to sync: the pull request below the stack has not merged.")
+				return
+			}
+			for _, r := range retargets {
+				fmt.Printf("Retargeted #%d from %s onto %s (#%d merged)\n", r.PullRequest.ID, r.From, r.To, r.Merged.ID)
+			}
+			fmt.Println()
+			printStack(stack, branch)
+			fmt.Printf("\nRebase the stack with:\n\n%s", notes)
+		})
+	},
+}
+
+// stackRepo returns the repository from --repo, or from the local git remotes.
+func stackRepo(cmd *cobra.Command) (workspace, repoSlug string) {
+	if repo, _ := cmd.Flags().GetString("repo"); repo != "" {
+		workspace, repoSlug, ok := strings.Cut(repo, "/")
+		if !ok || workspace == "" || repoSlug == "" {
+			fmt.Fprintf(os.Stderr, "Error: --repo must be workspace/repo-slug, got %q\n", repo)
+			os.Exit(1)
+		}
+		return workspace, repoSlug
+	}
+	workspace, repoSlug, err := bitbucket.GetLocalRepoInfo()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not infer workspace/repo from git: %v (use --repo)\n", err)
+		os.Exit(1)
+	}
+	return workspace, repoSlug
+}
+
+// stackBranch returns the branch argument, or the branch checked out.
+func stackBranch(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	branch, err := bitbucket.GetCurrentBranch()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	return branch
+}
+
+// printStack lists the stack bottom first, marking current.
+func printStack(stack *bitbucket.Stack, current string) {
+	KV("Base", stack.Base)
+	t := NewTable()
+	t.Header("", "ID", "Branch", "Target", "State", "Reviews", "Title")
+	for _, pr := range stack.PullRequests {
+		var branch, target string
+		if pr.Source.Branch != nil {
+			branch = pr.Source.Branch.Name
+		}
+		if pr.Destination.Branch != nil {
+			target = pr.Destination.Branch.Name
+		}
+		marker := ""
+		if branch == current {
+			marker = "*"
+		}
+		state := pr.State
+		if pr.Draft {
+			state = "DRAFT"
+		}
+		t.Row(marker, fmt.Sprintf("#%d", pr.ID), branch, target, state, reviewSummary(pr), Truncate(pr.Title, 50))
+	}
+	t.Flush()
+}
+
+// reviewSummary counts approvals and change requests on a pull request, or
+// returns "-" when its participants weren't fetched.
+func reviewSummary(pr bitbucket.PullRequest) string {
+	if pr.Participants == nil {
+		return "-"
+	}
+	approved, changes := 0, 0
+	for _, p := range pr.Participants {
+		switch {
+		case p.Approved:
+			approved++
+		case p.State == "changes_requested":
+			changes++
+		}
+	}
+	summary := fmt.Sprintf("%d approved", approved)
+	if changes > 0 {
+		summary += fmt.Sprintf(", %d changes requested", changes)
+	}
+	return summary
+}
+
+func init() {
+	RootCmd.AddCommand(stackCmd)
+	stackCmd.AddCommand(stackCreateCmd)
+	stackCmd.AddCommand(stackShowCmd)
+	stackCmd.AddCommand(stackSyncCmd)
+
+	stackCmd.PersistentFlags().String("repo", "", "Repository as workspace/repo-slug (default: inferred from git)")
+
+	stackCreateCmd.Flags().String("base", "", "Branch the bottom pull request targets (default: the repository's main branch)")
+	stackCreateCmd.Flags().Bool("draft", false, "Create the pull requests as drafts")
+
+	stackSyncCmd.Flags().Bool("comment", false, "Post the rebase commands on the retargeted pull request")
+}
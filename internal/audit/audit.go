@@ -0,0 +1,250 @@
+// Package audit records mutating MCP tool calls to an append-only JSONL log.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes recorded for each entry.
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+	OutcomeRefused = "refused"
+)
+
+const (
+	// DefaultMaxBytes is the size at which the active log file is rotated.
+	DefaultMaxBytes int64 = 10 << 20
+	// DefaultMaxBackups is the number of rotated files kept alongside the active one.
+	DefaultMaxBackups = 5
+)
+
+// Entry is a single audited tool call.
+type Entry struct {
+	Time      time.Time         `json:"time"`
+	Profile   string            `json:"profile,omitempty"`
+	User      string            `json:"user,omitempty"`
+	Tool      string            `json:"tool"`
+	Action    string            `json:"action"`
+	Workspace string            `json:"workspace,omitempty"`
+	RepoSlug  string            `json:"repo_slug,omitempty"`
+	Arguments map[string]any    `json:"arguments,omitempty"`
+	Outcome   string            `json:"outcome"`
+	Error     string            `json:"error,omitempty"`
+	Objects   map[string]string `json:"objects,omitempty"`
+}
+
+// DefaultPath returns the default audit log location next to the credentials file.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("getting home dir: %w", err)
+	}
+	return filepath.Join(home, ".config", "bbkt", "audit.jsonl"), nil
+}
+
+// Logger appends entries to a JSONL file, rotating it by size.
+type Logger struct {
+	path       string
+	maxBytes   int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Open opens (or creates) the audit log at path for appending.
+// Zero maxBytes or maxBackups select the defaults.
+func Open(path string, maxBytes int64, maxBackups int) (*Logger, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if maxBackups <= 0 {
+		maxBackups = DefaultMaxBackups
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating audit log dir: %w", err)
+	}
+
+	l := &Logger{path: path, maxBytes: maxBytes, maxBackups: maxBackups}
+	if err := l.openFile(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Path returns the active log file path.
+func (l *Logger) Path() string {
+	return l.path
+}
+
+func (l *Logger) openFile() error {
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat audit log: %w", err)
+	}
+	l.file = f
+	l.size = info.Size()
+	return nil
+}
+
+// Write appends an entry, rotating the file first if it would exceed the size limit.
+func (l *Logger) Write(e *Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("writing audit entry: %w", err)
+	}
+	return nil
+}
+
+// rotate shifts path.N to path.N+1 (dropping the oldest) and starts a fresh file.
+func (l *Logger) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("closing audit log: %w", err)
+	}
+
+	for i := l.maxBackups - 1; i >= 1; i-- {
+		src := fmt.Sprintf("%s.%d", l.path, i)
+		if _, err := os.Stat(src); err == nil {
+			_ = os.Rename(src, fmt.Sprintf("%s.%d", l.path, i+1))
+		}
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return fmt.Errorf("rotating audit log: %w", err)
+	}
+
+	return l.openFile()
+}
+
+// Close closes the underlying file.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Hash returns a stable, non-reversible fingerprint for sensitive values.
+func Hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Filter selects entries when reading the log. Zero fields match everything.
+type Filter struct {
+	Tool    string
+	Action  string
+	Outcome string
+	Profile string
+	Match   func(workspace, repoSlug string) bool
+	Since   time.Time
+}
+
+func (f *Filter) matches(e *Entry) bool {
+	switch {
+	case f.Tool != "" && e.Tool != f.Tool:
+		return false
+	case f.Action != "" && e.Action != f.Action:
+		return false
+	case f.Outcome != "" && e.Outcome != f.Outcome:
+		return false
+	case f.Profile != "" && e.Profile != f.Profile:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case f.Match != nil && !f.Match(e.Workspace, e.RepoSlug):
+		return false
+	}
+	return true
+}
+
+// Read returns matching entries from the log and its rotated backups, oldest first.
+func Read(path string, f Filter) ([]Entry, error) {
+	// Backups are numbered newest-first, so walk them from the highest suffix down.
+	var backups []int
+	matches, _ := filepath.Glob(path + ".*")
+	for _, m := range matches {
+		if n, err := strconv.Atoi(strings.TrimPrefix(m, path+".")); err == nil && n > 0 {
+			backups = append(backups, n)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(backups)))
+
+	files := make([]string, 0, len(backups)+1)
+	for _, n := range backups {
+		files = append(files, fmt.Sprintf("%s.%d", path, n))
+	}
+	files = append(files, path)
+
+	var entries []Entry
+	for _, name := range files {
+		if err := readFile(name, &f, &entries); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+	}
+	return entries, nil
+}
+
+func readFile(name string, f *Filter, out *[]Entry) error {
+	file, err := os.Open(name) //nolint:gosec // path comes from the user's own config
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4<<20)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // skip partially written lines
+		}
+		if f.matches(&e) {
+			*out = append(*out, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package bitbucket
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GetPRActivity fetches the full activity log of a pull request: updates,
+// approvals, change requests and comments, newest first as the API returns them.
+func (c *Client) GetPRActivity(args PullRequestActionArgs) (*Paginated[PRActivity], error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
+		return nil, fmt.Errorf("workspace, repo_slug, and pr_id are required")
+	}
+
+	return GetAllPaginated[PRActivity](c, fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/activity?pagelen=50",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID), nil)
+}
+
+// PRTimelineEvent is one step in the history of a pull request.
+type PRTimelineEvent struct {
+	Date   time.Time `json:"date"`
+	Actor  *User     `json:"actor,omitempty"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// stateActions names the event for a pull request entering a state.
+var stateActions = map[string]string{
+	"MERGED":     "merged",
+	"DECLINED":   "declined",
+	"SUPERSEDED": "superseded",
+	"OPEN":       "reopened",
+}
+
+// PRTimeline turns an activity log into chronological events. The API only
+// records snapshots for updates, so pushes, retitles, retargets and reviewer
+// changes are found by comparing each snapshot with the one before it.
+func PRTimeline(activity []PRActivity) []PRTimelineEvent {
+	var events []PRTimelineEvent
+	var prev *PRUpdate
+
+	// Entries arrive newest first; walk them oldest first so snapshots compare
+	// against their predecessor.
+	for _, a := range slices.Backward(activity) {
+		switch {
+		case a.Update != nil:
+			events = append(events, updateEvents(prev, a.Update)...)
+			prev = a.Update
+		case a.Approval != nil:
+			events = append(events, PRTimelineEvent{Date: a.Approval.Date, Actor: a.Approval.User, Action: "approved"})
+		case a.ChangesRequested != nil:
+			events = append(events, PRTimelineEvent{Date: a.ChangesRequested.Date, Actor: a.ChangesRequested.User, Action: "requested changes"})
+		case a.Comment != nil:
+			events = append(events, commentEvent(a.Comment))
+		}
+	}
+
+	slices.SortStableFunc(events, func(a, b PRTimelineEvent) int { return a.Date.Compare(b.Date) })
+	return events
+}
+
+func updateEvents(prev, u *PRUpdate) []PRTimelineEvent {
+	event := func(action, detail string) PRTimelineEvent {
+		return PRTimelineEvent{Date: u.Date, Actor: u.Author, Action: action, Detail: detail}
+	}
+
+	if prev == nil {
+		if u.State != "" && u.State != "OPEN" {
+			return []PRTimelineEvent{event(stateActions[u.State], u.Reason)}
+		}
+		return []PRTimelineEvent{event("opened", fmt.Sprintf("%q (%s → %s)", u.Title, endpointBranch(u.Source), endpointBranch(u.Destination)))}
+	}
+
+	var events []PRTimelineEvent
+	if u.State != prev.State {
+		if action, ok := stateActions[u.State]; ok {
+			events = append(events, event(action, u.Reason))
+		}
+	}
+	if hash := endpointHash(u.Source); hash != "" && hash != endpointHash(prev.Source) {
+		events = append(events, event("pushed", "source now at "+hash))
+	}
+	if u.Title != prev.Title {
+		events = append(events, event("retitled", fmt.Sprintf("%q → %q", prev.Title, u.Title)))
+	}
+	if u.Description != prev.Description {
+		events = append(events, event("edited the description", ""))
+	}
+	if branch := endpointBranch(u.Destination); branch != endpointBranch(prev.Destination) {
+		events = append(events, event("retargeted", "onto "+branch))
+	}
+	if detail := reviewerChanges(prev.Reviewers, u.Reviewers); detail != "" {
+		events = append(events, event("changed reviewers", detail))
+	}
+	if len(events) == 0 {
+		events = append(events, event("updated", ""))
+	}
+	return events
+}
+
+func commentEvent(c *PRComment) PRTimelineEvent {
+	action := "commented"
+	switch {
+	case c.Deleted:
+		action = "deleted a comment"
+	case c.Parent != nil && c.Parent.ID != 0:
+		action = "replied"
+	}
+
+	var detail []string
+	if c.Inline != nil && c.Inline.Path != "" {
+		loc := c.Inline.Path
+		if line := c.Inline.To; line != nil {
+			loc += fmt.Sprintf(":%d", *line)
+		} else if line := c.Inline.From; line != nil {
+			loc += fmt.Sprintf(":%d", *line)
+		}
+		detail = append(detail, loc)
+	}
+	if !c.Deleted {
+		if text := strings.TrimSpace(c.Content.Raw); text != "" {
+			detail = append(detail, text)
+		}
+	}
+	return PRTimelineEvent{Date: c.CreatedOn, Actor: c.User, Action: action, Detail: strings.Join(detail, ": ")}
+}
+
+// reviewerChanges describes who was added to or removed from before to get after.
+func reviewerChanges(before, after []User) string {
+	var added, removed []string
+	for _, u := range after {
+		if !slices.ContainsFunc(before, func(b User) bool { return b.UUID == u.UUID }) {
+			added = append(added, userName(u))
+		}
+	}
+	for _, u := range before {
+		if !slices.ContainsFunc(after, func(a User) bool { return a.UUID == u.UUID }) {
+			removed = append(removed, userName(u))
+		}
+	}
+
+	var parts []string
+	if len(added) > 0 {
+		parts = append(parts, "added "+strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "removed "+strings.Join(removed, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+func userName(u User) string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Nickname
+}
+
+func endpointBranch(e PREndpoint) string {
+	if e.Branch == nil {
+		return ""
+	}
+	return e.Branch.Name
+}
+
+func endpointHash(e PREndpoint) string {
+	if e.Commit == nil {
+		return ""
+	}
+	hash := e.Commit.Hash
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return hash
+}
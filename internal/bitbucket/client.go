@@ -65,16 +65,38 @@ func NewClientFromCredentials(creds *Credentials) *Client {
 	return c
 }
 
-// ensureValidToken checks if the OAuth token is expired and refreshes if needed.
-func (c *Client) ensureValidToken() error {
-	if c.oauthCreds == nil {
-		return nil
+// SetCredentials switches the client to creds, e.g. after the active profile
+// changes, and forgets the scopes cached for the previous credentials.
+func (c *Client) SetCredentials(creds *Credentials) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.oauthCreds = creds
+	c.token, c.username, c.password = "", "", ""
+	if creds.IsOAuth() {
+		c.token = creds.AccessToken
+	} else if creds.IsAPIToken() {
+		c.username = creds.Email
+		c.password = creds.APIToken
 	}
+	c.apiTokenScopes = nil
+	c.scopesFetched = false
+}
+
+// ResetScopes forgets the cached scopes so the next Scopes call asks the API again.
+func (c *Client) ResetScopes() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.apiTokenScopes = nil
+	c.scopesFetched = false
+}
 
+// ensureValidToken checks if the OAuth token is expired and refreshes if needed.
+func (c *Client) ensureValidToken() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if !c.oauthCreds.IsExpired() {
+	if c.oauthCreds == nil || !c.oauthCreds.IsExpired() {
 		return nil
 	}
 
@@ -104,10 +126,14 @@ func (c *Client) do(method, path string, bodyData []byte, contentType string) (*
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
-	} else if c.username != "" && c.password != "" {
-		req.SetBasicAuth(c.username, c.password)
+	c.mu.Lock()
+	token, username, password := c.token, c.username, c.password
+	c.mu.Unlock()
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if username != "" && password != "" {
+		req.SetBasicAuth(username, password)
 	}
 
 	if contentType != "" {
@@ -193,17 +219,19 @@ func (c *Client) GetWithScopes(path string) (body []byte, scopes string, err err
 // Scopes dynamically fetches and returns the token scopes by calling the API if not already cached.
 func (c *Client) Scopes() ([]string, error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	if c.scopesFetched {
+		defer c.mu.Unlock()
 		return c.apiTokenScopes, nil
 	}
 
 	if c.oauthCreds != nil && c.oauthCreds.Scopes != "" {
+		defer c.mu.Unlock()
 		c.apiTokenScopes = parseScopesString(c.oauthCreds.Scopes)
 		c.scopesFetched = true
 		return c.apiTokenScopes, nil
 	}
+	// The requests below take the lock themselves.
+	c.mu.Unlock()
 
 	_, scopesStr, _ := c.GetWithScopes("/workspace")
 	if scopesStr == "" {
@@ -215,11 +243,39 @@ func (c *Client) Scopes() ([]string, error) {
 		return nil, fmt.Errorf("failed to reliably fetch token scopes: API did not return X-OAuth-Scopes header")
 	}
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.apiTokenScopes = parseScopesString(scopesStr)
 	c.scopesFetched = true
 	return c.apiTokenScopes, nil
 }
 
+// Verify checks the credentials against /user and caches the granted scopes.
+// A 403 means the token is valid but lacks account scopes, so Verify returns a
+// nil user without error.
+func (c *Client) Verify() (*User, error) {
+	data, scopesStr, err := c.GetWithScopes("/user")
+	if err != nil && !strings.HasPrefix(err.Error(), "403 Forbidden") {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if scopesStr != "" {
+		c.apiTokenScopes = parseScopesString(scopesStr)
+		c.scopesFetched = true
+	}
+	c.mu.Unlock()
+
+	if data == nil {
+		return nil, nil
+	}
+	var user User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return nil, fmt.Errorf("parsing user: %w", err)
+	}
+	return &user, nil
+}
+
 func parseScopesString(s string) []string {
 	if s == "" {
 		return nil
@@ -279,21 +335,51 @@ func (c *Client) Post(path string, body interface{}) ([]byte, error) {
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respData))
+		return nil, parseAPIError(resp.StatusCode, respData)
 	}
 
 	return respData, nil
 }
 
+// PostRaw performs a POST request with a JSON body and also returns the status
+// code and Location header, for endpoints that answer 202 Accepted with a task
+// to poll.
+func (c *Client) PostRaw(path string, body interface{}) (data []byte, status int, location string, err error) {
+	var bodyData []byte
+	if body != nil {
+		if bodyData, err = json.Marshal(body); err != nil {
+			return nil, 0, "", fmt.Errorf("marshaling body: %w", err)
+		}
+	}
+
+	resp, err := c.do(http.MethodPost, path, bodyData, "application/json")
+	if err != nil {
+		return nil, 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("reading response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, resp.StatusCode, "", parseAPIError(resp.StatusCode, respData)
+	}
+
+	return respData, resp.StatusCode, resp.Header.Get("Location"), nil
+}
+
 // PostMultipart performs a POST request using multipart/form-data.
 // It takes a map of form fields and a map of file fields (where key is the field name and value is the file content).
-func (c *Client) PostMultipart(path string, fields map[string]string, files map[string][]byte) ([]byte, error) {
+// The Location header is returned too: commits made through /src answer with an empty body and point at the new commit there.
+func (c *Client) PostMultipart(path string, fields map[string]string, files map[string][]byte) (data []byte, location string, err error) {
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
 
 	for key, val := range fields {
 		if err := w.WriteField(key, val); err != nil {
-			return nil, fmt.Errorf("writing field %s: %w", key, err)
+			return nil, "", fmt.Errorf("writing field %s: %w", key, err)
 		}
 	}
 
@@ -302,33 +388,33 @@ func (c *Client) PostMultipart(path string, fields map[string]string, files map[
 		// We use CreateFormFile with the key as both fieldname and filename.
 		fw, err := w.CreateFormFile(key, key)
 		if err != nil {
-			return nil, fmt.Errorf("creating form file %s: %w", key, err)
+			return nil, "", fmt.Errorf("creating form file %s: %w", key, err)
 		}
 		if _, err := fw.Write(fileBytes); err != nil {
-			return nil, fmt.Errorf("writing file %s: %w", key, err)
+			return nil, "", fmt.Errorf("writing file %s: %w", key, err)
 		}
 	}
 
 	if err := w.Close(); err != nil {
-		return nil, fmt.Errorf("closing multipart writer: %w", err)
+		return nil, "", fmt.Errorf("closing multipart writer: %w", err)
 	}
 
 	resp, err := c.do(http.MethodPost, path, b.Bytes(), w.FormDataContentType())
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
 	respData, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
+		return nil, "", fmt.Errorf("reading response: %w", err)
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respData))
+		return nil, "", parseAPIError(resp.StatusCode, respData)
 	}
 
-	return respData, nil
+	return respData, resp.Header.Get("Location"), nil
 }
 
 // Put performs a PUT request with a JSON body.
@@ -356,7 +442,7 @@ func (c *Client) Put(path string, body interface{}) ([]byte, error) {
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(respData))
+		return nil, parseAPIError(resp.StatusCode, respData)
 	}
 
 	return respData, nil
@@ -403,6 +489,38 @@ func GetPaginated[T any](c *Client, path string) (*Paginated[T], error) {
 	return &result, nil
 }
 
+// maxAllPages bounds GetAllPaginated so a runaway listing cannot loop forever.
+const maxAllPages = 100
+
+// GetAllPaginated follows `next` links from path and returns every value in a
+// single page. onPage, when non-nil, is called after each page with the number
+// of values fetched so far and the total reported by the API (0 if unknown).
+func GetAllPaginated[T any](c *Client, path string, onPage func(fetched, total int)) (*Paginated[T], error) {
+	all := &Paginated[T]{Page: 1}
+	for pages := 0; path != ""; pages++ {
+		if pages == maxAllPages {
+			return nil, fmt.Errorf("more than %d pages of results; narrow the query", maxAllPages)
+		}
+
+		page, err := GetPaginated[T](c, path)
+		if err != nil {
+			return nil, err
+		}
+		all.Values = append(all.Values, page.Values...)
+		all.Size = page.Size
+		if onPage != nil {
+			onPage(len(all.Values), page.Size)
+		}
+
+		path = strings.TrimPrefix(page.Next, c.baseURL)
+	}
+	all.PageLen = len(all.Values)
+	if all.Size == 0 {
+		all.Size = len(all.Values)
+	}
+	return all, nil
+}
+
 // GetJSON performs a GET and unmarshals the JSON response.
 func GetJSON[T any](c *Client, path string) (*T, error) {
 	data, err := c.Get(path)
@@ -423,9 +541,26 @@ func QueryEscape(s string) string {
 	return url.PathEscape(s)
 }
 
-func parseAPIError(statusCode int, body []byte) error {
-	if statusCode == http.StatusForbidden {
-		return fmt.Errorf("403 Forbidden: Permission denied. Ensure your Bitbucket App Password has the required scopes for this operation. Additional details: %s", string(body))
+// bbqlString quotes s as a string literal in a Bitbucket query (BBQL),
+// escaping backslashes and double quotes.
+func bbqlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// StatusError is an error response from the Bitbucket API. Callers that need to
+// tell statuses apart should use errors.As rather than match the message.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	if e.StatusCode == http.StatusForbidden {
+		return fmt.Sprintf("403 Forbidden: Permission denied. Ensure your Bitbucket App Password has the required scopes for this operation. Additional details: %s", e.Body)
 	}
-	return fmt.Errorf("API error %d: %s", statusCode, string(body))
+	return fmt.Sprintf("API error %d: %s", e.StatusCode, e.Body)
+}
+
+func parseAPIError(statusCode int, body []byte) error {
+	return &StatusError{StatusCode: statusCode, Body: string(body)}
 }
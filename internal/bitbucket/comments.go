@@ -35,14 +35,16 @@ func (c *Client) ListPRComments(args ListPRCommentsArgs) (*Paginated[PRComment],
 }
 
 type CreatePRCommentArgs struct {
-	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
-	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
-	PRID      int    `json:"pr_id" jsonschema:"Pull request ID"`
-	Content   string `json:"content" jsonschema:"Markdown content of the comment"`
-	FilePath  string `json:"file_path,omitempty" jsonschema:"File path for inline comments"`
-	LineTo    int    `json:"line_to,omitempty" jsonschema:"Line number the comment applies to (for new/modified lines)"`
-	LineFrom  int    `json:"line_from,omitempty" jsonschema:"Line number the comment applies to (for deleted lines)"`
-	ParentID  int    `json:"parent_id,omitempty" jsonschema:"Parent comment ID to reply to"`
+	Workspace     string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug      string `json:"repo_slug" jsonschema:"Repository slug"`
+	PRID          int    `json:"pr_id" jsonschema:"Pull request ID"`
+	Content       string `json:"content" jsonschema:"Markdown content of the comment"`
+	FilePath      string `json:"file_path,omitempty" jsonschema:"File path for inline comments"`
+	LineTo        int    `json:"line_to,omitempty" jsonschema:"Line number the comment applies to (for new/modified lines)"`
+	LineFrom      int    `json:"line_from,omitempty" jsonschema:"Line number the comment applies to (for deleted lines)"`
+	StartLineTo   int    `json:"start_line_to,omitempty" jsonschema:"First new-side line of a multi-line comment ending at line_to"`
+	StartLineFrom int    `json:"start_line_from,omitempty" jsonschema:"First old-side line of a multi-line comment ending at line_from"`
+	ParentID      int    `json:"parent_id,omitempty" jsonschema:"Parent comment ID to reply to"`
 }
 
 // CreatePRComment creates a comment on a pull request.
@@ -68,6 +70,14 @@ func (c *Client) CreatePRComment(args CreatePRCommentArgs) (*PRComment, error) {
 			lineFrom := args.LineFrom
 			body.Inline.From = &lineFrom
 		}
+		if args.StartLineTo > 0 {
+			startTo := args.StartLineTo
+			body.Inline.StartTo = &startTo
+		}
+		if args.StartLineFrom > 0 {
+			startFrom := args.StartLineFrom
+			body.Inline.StartFrom = &startFrom
+		}
 	}
 
 	// Reply to parent comment
@@ -76,19 +76,26 @@ type GetDiffArgs struct {
 }
 
 // GetDiff gets the diff between two revisions or for a single commit.
-func (c *Client) GetDiff(args GetDiffArgs) ([]byte, error) {
+func (c *Client) GetDiff(args GetDiffArgs, opts DiffOptions) ([]byte, error) {
 	if args.Workspace == "" || args.RepoSlug == "" || args.Spec == "" {
 		return nil, fmt.Errorf("workspace, repo_slug, and spec are required")
 	}
 
 	endpoint := fmt.Sprintf("/repositories/%s/%s/diff/%s",
 		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.Spec)
+	q := opts.query()
 	if args.Path != "" {
-		endpoint += "?path=" + args.Path
+		q.Set("path", args.Path)
+	}
+	if len(q) > 0 {
+		endpoint += "?" + q.Encode()
 	}
 
 	raw, _, err := c.GetRaw(endpoint)
-	return raw, err
+	if err != nil {
+		return nil, err
+	}
+	return opts.filter(raw), nil
 }
 
 type GetDiffStatArgs struct {
@@ -0,0 +1,425 @@
+package bitbucket
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Sides of a diff an inline comment can be anchored to.
+const (
+	DiffSideNew = "new"
+	DiffSideOld = "old"
+)
+
+// DiffOptions control how a diff is generated. The zero value keeps
+// Bitbucket's defaults.
+type DiffOptions struct {
+	Context          *int     // lines of context around each change (default 3)
+	IgnoreWhitespace bool     // ignore whitespace-only changes
+	ExcludeBinary    bool     // leave out binary files
+	Paths            []string // keep only files matching one of these paths, directories or globs
+}
+
+// String identifies the options, e.g. to tell cached diffs apart.
+func (o DiffOptions) String() string {
+	return o.query().Encode() + "&paths=" + strings.Join(o.Paths, ",")
+}
+
+func (o DiffOptions) query() url.Values {
+	q := url.Values{}
+	if o.Context != nil {
+		q.Set("context", strconv.Itoa(*o.Context))
+	}
+	if o.IgnoreWhitespace {
+		q.Set("ignore_whitespace", "true")
+	}
+	if o.ExcludeBinary {
+		q.Set("binary", "false")
+	}
+	return q
+}
+
+// filter drops the files the options exclude. Paths are matched here rather
+// than through the API's path parameter, which only accepts exact file paths.
+func (o DiffOptions) filter(diff []byte) []byte {
+	if len(o.Paths) == 0 && !o.ExcludeBinary {
+		return diff
+	}
+	var b bytes.Buffer
+	for _, f := range ParseDiff(diff) {
+		if o.keep(f) {
+			b.WriteString(f.Text)
+		}
+	}
+	return b.Bytes()
+}
+
+func (o DiffOptions) keep(f FileDiff) bool {
+	if o.ExcludeBinary && f.Binary {
+		return false
+	}
+	if len(o.Paths) == 0 {
+		return true
+	}
+	for _, pattern := range o.Paths {
+		if (f.NewPath != "" && MatchDiffPath(pattern, f.NewPath)) || (f.OldPath != "" && MatchDiffPath(pattern, f.OldPath)) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDiffPath reports whether a changed file's path matches pattern: the
+// file itself, a directory containing it, or a glob such as "src/api/*.go".
+// A glob without a slash, like "*.go", matches the file name in any directory.
+func MatchDiffPath(pattern, p string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return false
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return p == pattern || strings.HasPrefix(p, pattern+"/")
+	}
+	if ok, _ := path.Match(pattern, p); ok {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(p))
+		return ok
+	}
+	return false
+}
+
+// FileDiff is one file's section of a unified diff. OldPath is empty for
+// added files and NewPath for deleted ones.
+type FileDiff struct {
+	OldPath      string     `json:"old_path,omitempty"`
+	NewPath      string     `json:"new_path,omitempty"`
+	Status       string     `json:"status"`
+	LinesAdded   int        `json:"lines_added"`
+	LinesRemoved int        `json:"lines_removed"`
+	Binary       bool       `json:"binary,omitempty"`
+	Hunks        []DiffHunk `json:"-"`
+	Text         string     `json:"diff"`
+}
+
+// Path returns the file's path after the change, or before it for deletions.
+func (f FileDiff) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// DiffHunk is one "@@" block of a file diff.
+type DiffHunk struct {
+	OldStart, OldLines int
+	NewStart, NewLines int
+}
+
+// lines returns the first and last line the hunk covers on side, or ok=false
+// when it covers none (e.g. the new side of a pure deletion).
+func (h DiffHunk) lines(side string) (first, last int, ok bool) {
+	start, count := h.NewStart, h.NewLines
+	if side == DiffSideOld {
+		start, count = h.OldStart, h.OldLines
+	}
+	return start, start + count - 1, count > 0
+}
+
+// ParseDiff splits a unified diff, as returned by GetPRDiff, into files with
+// their hunks, line counts and text.
+func ParseDiff(diff []byte) []FileDiff {
+	var files []FileDiff
+	var text strings.Builder
+	oldLeft, newLeft := 0, 0
+
+	finish := func() {
+		if len(files) == 0 {
+			return
+		}
+		f := &files[len(files)-1]
+		f.Text = text.String()
+		text.Reset()
+		switch {
+		case f.OldPath == "":
+			f.Status = "added"
+		case f.NewPath == "":
+			f.Status = "removed"
+		case f.OldPath != f.NewPath:
+			f.Status = "renamed"
+		default:
+			f.Status = "modified"
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Inside a hunk every line is content, even one that looks like a header.
+		if oldLeft > 0 || newLeft > 0 {
+			file := &files[len(files)-1]
+			switch {
+			case strings.HasPrefix(line, "+"):
+				newLeft--
+				file.LinesAdded++
+			case strings.HasPrefix(line, "-"):
+				oldLeft--
+				file.LinesRemoved++
+			case strings.HasPrefix(line, `\`):
+			default:
+				oldLeft--
+				newLeft--
+			}
+			text.WriteString(line + "\n")
+			continue
+		}
+
+		if strings.HasPrefix(line, "diff --git ") {
+			finish()
+			var f FileDiff
+			if a, b, ok := strings.Cut(strings.TrimPrefix(line, "diff --git "), " b/"); ok {
+				f.OldPath, f.NewPath = strings.TrimPrefix(a, "a/"), b
+			}
+			files = append(files, f)
+			text.WriteString(line + "\n")
+			continue
+		}
+		if len(files) == 0 {
+			continue
+		}
+
+		text.WriteString(line + "\n")
+		file := &files[len(files)-1]
+		switch {
+		case strings.HasPrefix(line, "Binary files ") || line == "GIT binary patch":
+			file.Binary = true
+		case strings.HasPrefix(line, "new file mode "):
+			file.OldPath = ""
+		case strings.HasPrefix(line, "deleted file mode "):
+			file.NewPath = ""
+		case strings.HasPrefix(line, "--- "):
+			file.OldPath = diffPath(strings.TrimPrefix(line, "--- "), "a/")
+		case strings.HasPrefix(line, "+++ "):
+			file.NewPath = diffPath(strings.TrimPrefix(line, "+++ "), "b/")
+		case strings.HasPrefix(line, "@@ "):
+			h, ok := parseHunkHeader(line)
+			if !ok {
+				continue
+			}
+			file.Hunks = append(file.Hunks, h)
+			oldLeft, newLeft = h.OldLines, h.NewLines
+		}
+	}
+	finish()
+	return files
+}
+
+func diffPath(p, prefix string) string {
+	if i := strings.IndexByte(p, '\t'); i >= 0 {
+		p = p[:i]
+	}
+	if p == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(p, prefix)
+}
+
+// parseHunkHeader parses "@@ -old[,count] +new[,count] @@ ...".
+func parseHunkHeader(line string) (DiffHunk, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return DiffHunk{}, false
+	}
+	oldStart, oldLines, ok1 := parseRange(fields[1], "-")
+	newStart, newLines, ok2 := parseRange(fields[2], "+")
+	if !ok1 || !ok2 {
+		return DiffHunk{}, false
+	}
+	return DiffHunk{OldStart: oldStart, OldLines: oldLines, NewStart: newStart, NewLines: newLines}, true
+}
+
+func parseRange(s, sign string) (start, count int, ok bool) {
+	s, ok = strings.CutPrefix(s, sign)
+	if !ok {
+		return 0, 0, false
+	}
+	startStr, countStr, hasCount := strings.Cut(s, ",")
+	start, err := strconv.Atoi(startStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, count, true
+}
+
+// InlineTarget is where a caller wants an inline comment to go.
+type InlineTarget struct {
+	Path    string
+	Side    string // DiffSideNew (default) or DiffSideOld
+	Line    int
+	EndLine int // last line of a multi-line range; 0 for a single line
+	Snap    bool
+}
+
+// InlineAnchor is an inline comment location known to be in the diff.
+type InlineAnchor struct {
+	Path  string
+	Side  string
+	Start int
+	End   int
+	Note  string // explains how the target was moved, when it was snapped
+}
+
+// Apply sets the inline location of a comment to the anchor.
+func (a InlineAnchor) Apply(args *CreatePRCommentArgs) {
+	args.FilePath = a.Path
+	args.LineFrom, args.LineTo, args.StartLineFrom, args.StartLineTo = 0, 0, 0, 0
+	start := 0
+	if a.Start != a.End {
+		start = a.Start
+	}
+	if a.Side == DiffSideOld {
+		args.LineFrom, args.StartLineFrom = a.End, start
+	} else {
+		args.LineTo, args.StartLineTo = a.End, start
+	}
+}
+
+// AnchorInline checks that t falls inside a hunk of the diff. A target outside
+// every hunk is rejected with the ranges that can be commented on, or, with
+// Snap, moved into the nearest hunk. A range must lie within a single hunk.
+func AnchorInline(files []FileDiff, t InlineTarget) (*InlineAnchor, error) {
+	side := t.Side
+	if side == "" {
+		side = DiffSideNew
+	}
+	if side != DiffSideNew && side != DiffSideOld {
+		return nil, fmt.Errorf("invalid side '%s' (expected '%s' or '%s')", t.Side, DiffSideNew, DiffSideOld)
+	}
+	if t.Line <= 0 {
+		return nil, fmt.Errorf("line must be positive")
+	}
+	end := t.EndLine
+	if end == 0 {
+		end = t.Line
+	}
+	if end < t.Line {
+		return nil, fmt.Errorf("end line %d is before start line %d", end, t.Line)
+	}
+
+	file := findFileDiff(files, t.Path)
+	if file == nil {
+		return nil, fmt.Errorf("%s is not changed in this pull request; changed files: %s", t.Path, changedFiles(files))
+	}
+
+	type span struct{ first, last int }
+	var spans []span
+	for _, h := range file.Hunks {
+		if first, last, ok := h.lines(side); ok {
+			spans = append(spans, span{first, last})
+		}
+	}
+	if len(spans) == 0 {
+		other := DiffSideOld
+		if side == DiffSideOld {
+			other = DiffSideNew
+		}
+		return nil, fmt.Errorf("%s has no %s-side lines in this diff; use side '%s'", t.Path, side, other)
+	}
+
+	anchor := &InlineAnchor{Path: t.Path, Side: side, Start: t.Line, End: end}
+	for _, s := range spans {
+		if t.Line >= s.first && end <= s.last {
+			return anchor, nil
+		}
+	}
+
+	ranges := make([]string, len(spans))
+	for i, s := range spans {
+		ranges[i] = fmt.Sprintf("%d-%d", s.first, s.last)
+	}
+	if !t.Snap {
+		what := fmt.Sprintf("%s of %s (%s side) is outside the diff", lineRange(t.Line, end), t.Path, side)
+		if end != t.Line {
+			what = fmt.Sprintf("%s of %s (%s side) are not within one hunk", lineRange(t.Line, end), t.Path, side)
+		}
+		return nil, fmt.Errorf("%s; commentable lines: %s", what, strings.Join(ranges, ", "))
+	}
+
+	// Snap to the hunk closest to the start line, clamping the range into it.
+	best, bestDist := spans[0], -1
+	for _, s := range spans {
+		dist := 0
+		if t.Line < s.first {
+			dist = s.first - t.Line
+		} else if t.Line > s.last {
+			dist = t.Line - s.last
+		}
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = s, dist
+		}
+	}
+	anchor.Start = min(max(t.Line, best.first), best.last)
+	anchor.End = min(max(end, anchor.Start), best.last)
+	anchor.Note = fmt.Sprintf("moved to %s of %s (%s side), in the nearest hunk (%d-%d)",
+		lineRange(anchor.Start, anchor.End), t.Path, side, best.first, best.last)
+	return anchor, nil
+}
+
+func lineRange(start, end int) string {
+	if start == end {
+		return fmt.Sprintf("line %d", start)
+	}
+	return fmt.Sprintf("lines %d-%d", start, end)
+}
+
+func findFileDiff(files []FileDiff, path string) *FileDiff {
+	for i := range files {
+		if files[i].NewPath == path || (files[i].NewPath == "" && files[i].OldPath == path) {
+			return &files[i]
+		}
+	}
+	for i := range files {
+		if files[i].OldPath == path {
+			return &files[i]
+		}
+	}
+	return nil
+}
+
+func changedFiles(files []FileDiff) string {
+	const maxListed = 10
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path())
+	}
+	if len(paths) == 0 {
+		return "none"
+	}
+	if len(paths) > maxListed {
+		return strings.Join(paths[:maxListed], ", ") + fmt.Sprintf(" and %d more", len(paths)-maxListed)
+	}
+	return strings.Join(paths, ", ")
+}
+
+// AnchorPRComment checks an inline comment location against the pull request's diff.
+func (c *Client) AnchorPRComment(args PullRequestActionArgs, t InlineTarget) (*InlineAnchor, error) {
+	diff, err := c.GetPRDiff(args, DiffOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff: %v", err)
+	}
+	return AnchorInline(ParseDiff(diff), t)
+}
@@ -0,0 +1,335 @@
+package bitbucket
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sampleDiff has two hunks in main.go, the first with added lines that look
+// like file headers, a file ending without a newline and a deleted file.
+const sampleDiff = `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,6 @@
+ package main
+-// old
++// new
++--- not a header
++++ b/not/a/file
+
+ func main() {}
+@@ -20,3 +22,4 @@ func helper() {
+ a
++b
+ c
+ d
+diff --git a/a.txt b/a.txt
+index 3333333..4444444 100644
+--- a/a.txt
++++ b/a.txt
+@@ -10,2 +10,2 @@
+ keep
+-old last
+\ No newline at end of file
++new last
+\ No newline at end of file
+diff --git a/gone.go b/gone.go
+deleted file mode 100644
+index 5555555..0000000
+--- a/gone.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package gone
+-
+`
+
+func TestParseDiff(t *testing.T) {
+	files := ParseDiff([]byte(sampleDiff))
+
+	type summary struct {
+		OldPath, NewPath string
+		Added, Removed   int
+		Hunks            []DiffHunk
+	}
+	want := []summary{
+		{"main.go", "main.go", 4, 1, []DiffHunk{{1, 4, 1, 6}, {20, 3, 22, 4}}},
+		{"a.txt", "a.txt", 1, 1, []DiffHunk{{10, 2, 10, 2}}},
+		{"gone.go", "", 0, 2, []DiffHunk{{1, 2, 0, 0}}},
+	}
+	var got []summary
+	for _, f := range files {
+		got = append(got, summary{f.OldPath, f.NewPath, f.LinesAdded, f.LinesRemoved, f.Hunks})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseDiff() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// The file texts split the diff without losing any of it.
+	var joined strings.Builder
+	for _, f := range files {
+		if !strings.HasPrefix(f.Text, "diff --git a/"+f.Path()+" ") {
+			t.Errorf("text of %s starts with %q", f.Path(), strings.SplitN(f.Text, "\n", 2)[0])
+		}
+		joined.WriteString(f.Text)
+	}
+	if joined.String() != sampleDiff {
+		t.Errorf("file texts do not add up to the diff:\n%s", joined.String())
+	}
+}
+
+func TestParseDiffEdgeCases(t *testing.T) {
+	tests := []struct {
+		name  string
+		diff  string
+		files int
+		hunks int
+	}{
+		{"empty", "", 0, 0},
+		{"preamble before first file", "junk\n" + sampleDiff, 3, 4},
+		{"malformed hunk header", "diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ bogus @@\n+x\n", 1, 0},
+		{"hunk without counts", "diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -3 +3 @@\n-a\n+b\n", 1, 1},
+		{"no trailing newline", "diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := ParseDiff([]byte(tt.diff))
+			hunks := 0
+			for _, f := range files {
+				hunks += len(f.Hunks)
+			}
+			if len(files) != tt.files || hunks != tt.hunks {
+				t.Errorf("got %d files, %d hunks; want %d, %d", len(files), hunks, tt.files, tt.hunks)
+			}
+		})
+	}
+}
+
+func TestAnchorInline(t *testing.T) {
+	files := ParseDiff([]byte(sampleDiff))
+
+	tests := []struct {
+		name    string
+		target  InlineTarget
+		want    *InlineAnchor
+		wantErr string
+	}{
+		{"line in hunk", InlineTarget{Path: "main.go", Line: 3},
+			&InlineAnchor{Path: "main.go", Side: DiffSideNew, Start: 3, End: 3}, ""},
+		{"range in hunk", InlineTarget{Path: "main.go", Line: 2, EndLine: 5},
+			&InlineAnchor{Path: "main.go", Side: DiffSideNew, Start: 2, End: 5}, ""},
+		{"last line of hunk", InlineTarget{Path: "main.go", Line: 25},
+			&InlineAnchor{Path: "main.go", Side: DiffSideNew, Start: 25, End: 25}, ""},
+		{"old side", InlineTarget{Path: "main.go", Side: DiffSideOld, Line: 21},
+			&InlineAnchor{Path: "main.go", Side: DiffSideOld, Start: 21, End: 21}, ""},
+		{"old side of deleted file", InlineTarget{Path: "gone.go", Side: DiffSideOld, Line: 1},
+			&InlineAnchor{Path: "gone.go", Side: DiffSideOld, Start: 1, End: 1}, ""},
+
+		{"between hunks", InlineTarget{Path: "main.go", Line: 10}, nil,
+			"line 10 of main.go (new side) is outside the diff; commentable lines: 1-6, 22-25"},
+		{"range across hunks", InlineTarget{Path: "main.go", Line: 5, EndLine: 23}, nil,
+			"lines 5-23 of main.go (new side) are not within one hunk"},
+		{"old line on new side", InlineTarget{Path: "main.go", Line: 21}, nil, "outside the diff"},
+		{"new side of deleted file", InlineTarget{Path: "gone.go", Line: 1}, nil,
+			"gone.go has no new-side lines in this diff; use side 'old'"},
+		{"unchanged file", InlineTarget{Path: "other.go", Line: 1}, nil,
+			"other.go is not changed in this pull request; changed files: main.go, a.txt, gone.go"},
+		{"invalid side", InlineTarget{Path: "main.go", Side: "left", Line: 1}, nil, "invalid side 'left'"},
+		{"zero line", InlineTarget{Path: "main.go"}, nil, "line must be positive"},
+		{"end before start", InlineTarget{Path: "main.go", Line: 3, EndLine: 2}, nil, "end line 2 is before start line 3"},
+
+		{"snap to nearer hunk before", InlineTarget{Path: "main.go", Line: 10, Snap: true},
+			&InlineAnchor{Path: "main.go", Side: DiffSideNew, Start: 6, End: 6,
+				Note: "moved to line 6 of main.go (new side), in the nearest hunk (1-6)"}, ""},
+		{"snap to nearer hunk after", InlineTarget{Path: "main.go", Line: 20, Snap: true},
+			&InlineAnchor{Path: "main.go", Side: DiffSideNew, Start: 22, End: 22,
+				Note: "moved to line 22 of main.go (new side), in the nearest hunk (22-25)"}, ""},
+		{"snap range clamps into hunk", InlineTarget{Path: "main.go", Line: 4, EndLine: 23, Snap: true},
+			&InlineAnchor{Path: "main.go", Side: DiffSideNew, Start: 4, End: 6,
+				Note: "moved to lines 4-6 of main.go (new side), in the nearest hunk (1-6)"}, ""},
+		{"snap past the end", InlineTarget{Path: "main.go", Line: 100, EndLine: 105, Snap: true},
+			&InlineAnchor{Path: "main.go", Side: DiffSideNew, Start: 25, End: 25,
+				Note: "moved to line 25 of main.go (new side), in the nearest hunk (22-25)"}, ""},
+		{"snap does not change sides", InlineTarget{Path: "gone.go", Line: 1, Snap: true}, nil, "use side 'old'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AnchorInline(files, tt.target)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("AnchorInline() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AnchorInline() error = %v", err)
+			}
+			if *got != *tt.want {
+				t.Errorf("AnchorInline() = %+v, want %+v", *got, *tt.want)
+			}
+		})
+	}
+}
+
+func TestInlineAnchorApply(t *testing.T) {
+	tests := []struct {
+		name   string
+		anchor InlineAnchor
+		want   CreatePRCommentArgs
+	}{
+		{"new line", InlineAnchor{Path: "a.go", Side: DiffSideNew, Start: 4, End: 4},
+			CreatePRCommentArgs{FilePath: "a.go", LineTo: 4}},
+		{"new range", InlineAnchor{Path: "a.go", Side: DiffSideNew, Start: 2, End: 4},
+			CreatePRCommentArgs{FilePath: "a.go", LineTo: 4, StartLineTo: 2}},
+		{"old range", InlineAnchor{Path: "a.go", Side: DiffSideOld, Start: 2, End: 4},
+			CreatePRCommentArgs{FilePath: "a.go", LineFrom: 4, StartLineFrom: 2}},
+	}
+	for _, tt := range tests {
+		// Stale locations from the caller are replaced.
+		args := CreatePRCommentArgs{FilePath: "old.go", LineFrom: 9, LineTo: 9, StartLineTo: 1}
+		tt.anchor.Apply(&args)
+		if !reflect.DeepEqual(args, tt.want) {
+			t.Errorf("%s: Apply() = %+v, want %+v", tt.name, args, tt.want)
+		}
+	}
+}
+
+// changesDiff adds, renames and changes the mode of files.
+const changesDiff = `diff --git a/logo.png b/logo.png
+new file mode 100644
+index 0000000..6666666
+Binary files /dev/null and b/logo.png differ
+diff --git a/old/name.go b/new/name.go
+similarity index 90%
+rename from old/name.go
+rename to new/name.go
+index 7777777..8888888 100644
+--- a/old/name.go
++++ b/new/name.go
+@@ -1 +1 @@
+-x
++y
+diff --git a/moved.go b/dir/moved.go
+similarity index 100%
+rename from moved.go
+rename to dir/moved.go
+diff --git a/src/api/new.go b/src/api/new.go
+new file mode 100644
+index 0000000..9999999
+--- /dev/null
++++ b/src/api/new.go
+@@ -0,0 +1,2 @@
++package api
++
+diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755
+`
+
+func TestParseDiffStatus(t *testing.T) {
+	type summary struct {
+		OldPath, NewPath, Status string
+		Binary                   bool
+	}
+	want := []summary{
+		{"main.go", "main.go", "modified", false},
+		{"a.txt", "a.txt", "modified", false},
+		{"gone.go", "", "removed", false},
+		{"", "logo.png", "added", true},
+		{"old/name.go", "new/name.go", "renamed", false},
+		{"moved.go", "dir/moved.go", "renamed", false},
+		{"", "src/api/new.go", "added", false},
+		{"run.sh", "run.sh", "modified", false},
+	}
+	var got []summary
+	for _, f := range ParseDiff([]byte(sampleDiff + changesDiff)) {
+		got = append(got, summary{f.OldPath, f.NewPath, f.Status, f.Binary})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseDiff() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestMatchDiffPath(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"main.go", "main.go", true},
+		{"main.go", "cmd/main.go", false},
+		{"src/api", "src/api/new.go", true},
+		{"src/api/", "src/api/new.go", true},
+		{"src/api", "src/api", true},
+		{"src/a", "src/api/new.go", false},
+		{"src/api", "src/apiv2/new.go", false},
+		{"src/api/*.go", "src/api/new.go", true},
+		{"src/api/*.go", "src/api/v2/new.go", false},
+		{"src/*/new.go", "src/api/new.go", true},
+		{"*.go", "src/api/new.go", true},
+		{"*.go", "logo.png", false},
+		{"new.go", "src/api/new.go", false},
+		{"?.txt", "a.txt", true},
+		{"[ab].txt", "docs/b.txt", true},
+		{"", "main.go", false},
+		{"/", "main.go", false},
+		{"[", "main.go", false},
+	}
+	for _, tt := range tests {
+		if got := MatchDiffPath(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchDiffPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestDiffOptionsFilter(t *testing.T) {
+	diff := []byte(sampleDiff + changesDiff)
+	tests := []struct {
+		name string
+		opts DiffOptions
+		want []string
+	}{
+		{"no options", DiffOptions{}, []string{"main.go", "a.txt", "gone.go", "logo.png", "new/name.go", "dir/moved.go", "src/api/new.go", "run.sh"}},
+		{"exclude binary", DiffOptions{ExcludeBinary: true}, []string{"main.go", "a.txt", "gone.go", "new/name.go", "dir/moved.go", "src/api/new.go", "run.sh"}},
+		{"deleted file by old path", DiffOptions{Paths: []string{"gone.go"}}, []string{"gone.go"}},
+		{"renamed file by old path", DiffOptions{Paths: []string{"old"}}, []string{"new/name.go"}},
+		{"renamed file by new path", DiffOptions{Paths: []string{"dir/"}}, []string{"dir/moved.go"}},
+		{"glob and directory", DiffOptions{Paths: []string{"*.txt", "src"}}, []string{"a.txt", "src/api/new.go"}},
+		{"glob with binary excluded", DiffOptions{Paths: []string{"*.png"}, ExcludeBinary: true}, nil},
+		{"no match", DiffOptions{Paths: []string{"nothing/"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered := tt.opts.filter(diff)
+			var got []string
+			for _, f := range ParseDiff(filtered) {
+				got = append(got, f.Path())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("filter() kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (DiffOptions{}).filter(diff); &got[0] != &diff[0] {
+		t.Errorf("filter() without options should return the diff as is")
+	}
+}
+
+func TestDiffOptionsQuery(t *testing.T) {
+	zero, ten := 0, 10
+	tests := []struct {
+		opts DiffOptions
+		want string
+	}{
+		{DiffOptions{}, ""},
+		{DiffOptions{Context: &zero}, "context=0"},
+		{DiffOptions{Context: &ten, IgnoreWhitespace: true, ExcludeBinary: true, Paths: []string{"src"}}, "binary=false&context=10&ignore_whitespace=true"},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.query().Encode(); got != tt.want {
+			t.Errorf("query() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package bitbucket
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeAgo formats t relative to now, e.g. "3h ago", or "-" for the zero time.
+func TimeAgo(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
@@ -0,0 +1,29 @@
+package bitbucket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeAgo(t *testing.T) {
+	now := time.Date(2026, 3, 4, 15, 4, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{59 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{47 * time.Hour, "47h ago"},
+		{72 * time.Hour, "3d ago"},
+	}
+	for _, tt := range tests {
+		if got := TimeAgo(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("TimeAgo(-%s) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+	if got := TimeAgo(time.Time{}, now); got != "-" {
+		t.Errorf("TimeAgo(zero) = %q, want \"-\"", got)
+	}
+}
@@ -72,3 +72,16 @@ func GetLocalRepoInfo() (workspace, repoSlug string, err error) {
 
 	return "", "", errors.New("no Bitbucket remotes found in the local repository")
 }
+
+// GetCurrentBranch returns the branch checked out in the local git repository.
+func GetCurrentBranch() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output()
+	if err != nil {
+		return "", errors.New("not a git repository")
+	}
+	branch := strings.TrimSpace(string(output))
+	if branch == "HEAD" {
+		return "", errors.New("HEAD is detached; pass a branch name")
+	}
+	return branch, nil
+}
@@ -0,0 +1,436 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// MergeStrategies are the strategies Bitbucket supports, used to validate a
+// strategy when the destination branch doesn't list its own.
+var MergeStrategies = []string{"merge_commit", "squash", "fast_forward", "squash_fast_forward", "rebase_fast_forward", "rebase_merge"}
+
+// How long to wait for an asynchronous merge, and how often to check on it.
+var (
+	mergePollInterval = 2 * time.Second
+	mergePollTimeout  = 5 * time.Minute
+)
+
+type MergePullRequestArgs struct {
+	Workspace         string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug          string `json:"repo_slug" jsonschema:"Repository slug"`
+	PRID              int    `json:"pr_id" jsonschema:"Pull request ID"`
+	CloseSourceBranch bool   `json:"close_source_branch,omitempty" jsonschema:"Close source branch"`
+	MergeStrategy     string `json:"merge_strategy,omitempty" jsonschema:"Merge strategy (merge_commit, squash, fast_forward, squash_fast_forward, rebase_fast_forward, rebase_merge; default: the destination branch's default)"`
+	Message           string `json:"message,omitempty" jsonschema:"Commit message"`
+	CheckOnly         bool   `json:"check_only,omitempty" jsonschema:"Run the merge checks without merging"`
+}
+
+// MergeCheck is the outcome of one merge precondition. Required checks are
+// enforced by a branch restriction on the destination, so Bitbucket would
+// refuse the merge while they fail; the others are advisory.
+type MergeCheck struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Required bool   `json:"required"`
+	Detail   string `json:"detail"`
+}
+
+// MergeResult reports the checks run before a merge and, once merged, the
+// merged pull request.
+type MergeResult struct {
+	PullRequest       *PullRequest `json:"pullrequest"`
+	Strategy          string       `json:"merge_strategy"`
+	AllowedStrategies []string     `json:"allowed_strategies,omitempty"`
+	Checks            []MergeCheck `json:"checks"`
+	Merged            bool         `json:"merged"`
+	CheckOnly         bool         `json:"check_only,omitempty"`
+	Note              string       `json:"note,omitempty"`
+
+	// restrictionsUnknown is set when the branch restrictions could not be
+	// read, so it is not known which checks Bitbucket enforces.
+	restrictionsUnknown bool
+}
+
+// Blocking returns the required checks that failed.
+func (r *MergeResult) Blocking() []MergeCheck {
+	var blocking []MergeCheck
+	for _, c := range r.Checks {
+		if c.Required && !c.Passed {
+			blocking = append(blocking, c)
+		}
+	}
+	return blocking
+}
+
+// Pending returns the checks a merge has to wait for: the required checks that
+// have not passed yet, or every failing check when the branch restrictions
+// could not be read.
+func (r *MergeResult) Pending() []MergeCheck {
+	var pending []MergeCheck
+	for _, c := range r.Checks {
+		if !c.Passed && (c.Required || r.restrictionsUnknown) {
+			pending = append(pending, c)
+		}
+	}
+	return pending
+}
+
+// Summary describes the pending checks in one line, e.g.
+// "waiting for approvals (0 of 1 required), builds (1 of 2 successful: ...)".
+func (r *MergeResult) Summary() string {
+	pending := r.Pending()
+	if len(pending) == 0 {
+		return "required checks passed"
+	}
+	parts := make([]string, len(pending))
+	for i, c := range pending {
+		parts[i] = fmt.Sprintf("%s (%s)", c.Name, c.Detail)
+	}
+	return "waiting for " + strings.Join(parts, ", ")
+}
+
+// MergePullRequest checks that a pull request can be merged with the chosen
+// strategy, then merges it and waits for Bitbucket to finish. It refuses when a
+// check required by the destination's branch restrictions fails; advisory
+// failures are reported in the result. With CheckOnly it stops after the checks.
+func (c *Client) MergePullRequest(ctx context.Context, args MergePullRequestArgs) (*MergeResult, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
+		return nil, fmt.Errorf("workspace, repo_slug, and pr_id are required")
+	}
+
+	result, _, err := c.preflightMerge(args)
+	if err != nil {
+		return nil, err
+	}
+	result.CheckOnly = args.CheckOnly
+	if blocking := result.Blocking(); len(blocking) > 0 {
+		failed := make([]string, len(blocking))
+		for i, b := range blocking {
+			failed[i] = fmt.Sprintf("%s (%s)", b.Name, b.Detail)
+		}
+		return result, fmt.Errorf("merge blocked by required checks: %s", strings.Join(failed, "; "))
+	}
+	if args.CheckOnly {
+		return result, nil
+	}
+
+	pr, err := c.mergeAndWait(ctx, args, result.Strategy)
+	if err != nil {
+		return result, err
+	}
+	result.PullRequest, result.Merged = pr, true
+	return result, nil
+}
+
+// preflightMerge fetches the pull request and evaluates the merge checks. It
+// also returns the build statuses of the source branch's head commit, which the
+// checks were based on.
+func (c *Client) preflightMerge(args MergePullRequestArgs) (*MergeResult, []CommitStatus, error) {
+	pr, err := c.GetPullRequest(GetPullRequestArgs{Workspace: args.Workspace, RepoSlug: args.RepoSlug, PRID: args.PRID})
+	if err != nil {
+		return nil, nil, err
+	}
+	if pr.State != "OPEN" {
+		return nil, nil, fmt.Errorf("pull request #%d is %s, not open", pr.ID, strings.ToLower(pr.State))
+	}
+
+	result := &MergeResult{PullRequest: pr, Strategy: args.MergeStrategy}
+	destination := ""
+	if b := pr.Destination.Branch; b != nil {
+		destination = b.Name
+		result.AllowedStrategies = b.MergeStrategies
+		if result.Strategy == "" {
+			result.Strategy = b.DefaultMergeStrategy
+		}
+	}
+	if result.Strategy == "" {
+		result.Strategy = "merge_commit"
+	}
+	allowed := result.AllowedStrategies
+	if len(allowed) == 0 {
+		allowed = MergeStrategies
+	}
+	if !slices.Contains(allowed, result.Strategy) {
+		return nil, nil, fmt.Errorf("merge strategy '%s' is not allowed on %s; allowed: %s", result.Strategy, destination, strings.Join(allowed, ", "))
+	}
+
+	restrictions, err := c.mergeRestrictions(args.Workspace, args.RepoSlug, destination)
+	if err != nil {
+		result.Note = "branch restrictions could not be read, so every check is advisory"
+		result.restrictionsUnknown = true
+	}
+
+	// The pull request's statuses cover every commit it ever had; only the
+	// head's count, as in Bitbucket's own merge checks.
+	var statuses []CommitStatus
+	if head := endpointHash(pr.Source); head != "" {
+		all, err := GetAllPaginated[CommitStatus](c, fmt.Sprintf("/repositories/%s/%s/commit/%s/statuses?pagelen=100",
+			QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), QueryEscape(head)), nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get build statuses: %v", err)
+		}
+		statuses = all.Values
+	}
+
+	result.Checks = mergeChecks(pr, statuses, restrictions)
+	return result, statuses, nil
+}
+
+// mergeChecks evaluates approvals, change requests, builds and tasks against
+// the destination's restrictions, keyed by kind.
+func mergeChecks(pr *PullRequest, statuses []CommitStatus, restrictions map[string]BranchRestriction) []MergeCheck {
+	required := func(kind string) (int, bool) {
+		r, ok := restrictions[kind]
+		if !ok {
+			return 0, false
+		}
+		if r.Value != nil {
+			return *r.Value, true
+		}
+		return 0, true
+	}
+
+	var approvals, changesRequested int
+	for _, p := range pr.Participants {
+		if p.Approved {
+			approvals++
+		}
+		if p.State == "changes_requested" {
+			changesRequested++
+		}
+	}
+
+	var checks []MergeCheck
+	if pr.Draft {
+		checks = append(checks, MergeCheck{Name: "draft", Passed: false, Required: true, Detail: "draft pull requests can't be merged"})
+	}
+
+	minApprovals, ok := required("require_approvals_to_merge")
+	checks = append(checks, MergeCheck{
+		Name:     "approvals",
+		Passed:   approvals >= max(minApprovals, 1),
+		Required: ok,
+		Detail:   fmt.Sprintf("%d of %d required", approvals, minApprovals),
+	})
+	if !ok {
+		checks[len(checks)-1].Detail = fmt.Sprintf("%d approvals", approvals)
+	}
+
+	_, ok = required("require_no_changes_requested")
+	checks = append(checks, MergeCheck{
+		Name:     "changes requested",
+		Passed:   changesRequested == 0,
+		Required: ok,
+		Detail:   fmt.Sprintf("%d reviewers requested changes", changesRequested),
+	})
+
+	counts := make(map[string]int)
+	var failing []string
+	for _, s := range statuses {
+		counts[s.State]++
+		if s.State != "SUCCESSFUL" {
+			failing = append(failing, fmt.Sprintf("%s %s", s.Name, strings.ToLower(s.State)))
+		}
+	}
+	minBuilds, ok := required("require_passing_builds_to_merge")
+	builds := MergeCheck{
+		Name:     "builds",
+		Passed:   len(failing) == 0 && counts["SUCCESSFUL"] >= minBuilds,
+		Required: ok,
+		Detail:   fmt.Sprintf("%d of %d successful", counts["SUCCESSFUL"], len(statuses)),
+	}
+	if len(failing) > 0 {
+		builds.Detail += ": " + strings.Join(failing, ", ")
+	}
+	checks = append(checks, builds)
+
+	_, ok = required("require_tasks_to_be_completed")
+	checks = append(checks, MergeCheck{
+		Name:     "tasks",
+		Passed:   pr.TaskCount == 0,
+		Required: ok,
+		Detail:   fmt.Sprintf("%d open", pr.TaskCount),
+	})
+	return checks
+}
+
+// mergeRestrictions returns the branch restrictions whose glob pattern covers
+// branch, keyed by kind. Reading restrictions needs repository admin access.
+// Restrictions that match by branching-model type are not resolved.
+func (c *Client) mergeRestrictions(workspace, repoSlug, branch string) (map[string]BranchRestriction, error) {
+	all, err := GetAllPaginated[BranchRestriction](c, fmt.Sprintf("/repositories/%s/%s/branch-restrictions?pagelen=100",
+		QueryEscape(workspace), QueryEscape(repoSlug)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]BranchRestriction)
+	for _, r := range all.Values {
+		if r.BranchMatchKind == "glob" && globMatch(r.Pattern, branch) {
+			matched[r.Kind] = r
+		}
+	}
+	return matched, nil
+}
+
+// globMatch matches branch names against Bitbucket's glob patterns, where *
+// also matches slashes.
+func globMatch(pattern, name string) bool {
+	re := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*") + "$"
+	ok, _ := regexp.MatchString(re, name)
+	return ok
+}
+
+// mergeTask is the status of an asynchronous merge.
+type mergeTask struct {
+	TaskStatus  string       `json:"task_status"`
+	MergeResult *PullRequest `json:"merge_result"`
+}
+
+// mergeAndWait requests the merge asynchronously and polls the task Bitbucket
+// returns until it finishes, so long merges don't time out the request.
+func (c *Client) mergeAndWait(ctx context.Context, args MergePullRequestArgs, strategy string) (*PullRequest, error) {
+	body := MergePRRequest{
+		Type:              "pullrequest",
+		CloseSourceBranch: args.CloseSourceBranch,
+		MergeStrategy:     strategy,
+		Message:           args.Message,
+	}
+
+	respData, status, location, err := c.PostRaw(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/merge?async=true",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge pull request: %v", err)
+	}
+
+	if status != http.StatusAccepted {
+		var pr PullRequest
+		if err := json.Unmarshal(respData, &pr); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %v", err)
+		}
+		return &pr, nil
+	}
+	if location == "" {
+		return nil, fmt.Errorf("merge accepted but Bitbucket returned no task to follow; check the pull request state")
+	}
+
+	taskPath := strings.TrimPrefix(location, c.baseURL)
+	deadline := time.Now().Add(mergePollTimeout)
+	for {
+		task, err := GetJSON[mergeTask](c, taskPath)
+		if err != nil {
+			return nil, fmt.Errorf("merge failed: %v", err)
+		}
+		switch task.TaskStatus {
+		case "SUCCESS":
+			if task.MergeResult == nil {
+				return nil, fmt.Errorf("merge finished without a result; check the pull request state")
+			}
+			return task.MergeResult, nil
+		case "PENDING":
+		default:
+			return nil, fmt.Errorf("merge task ended with status %s", task.TaskStatus)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("merge still in progress after %s; check the pull request state later", mergePollTimeout)
+		}
+		timer := time.NewTimer(mergePollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("stopped waiting for the merge (%v); it may still complete, check the pull request state", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// Defaults for MergeWhenReadyArgs, shared by the CLI and MCP front ends so they
+// wait the same way when the caller doesn't choose.
+const (
+	DefaultMergeWhenReadyTimeout  = 30 * time.Minute
+	DefaultMergeWhenReadyInterval = 30 * time.Second
+)
+
+type MergeWhenReadyArgs struct {
+	MergePullRequestArgs
+	Timeout  time.Duration // zero or less means DefaultMergeWhenReadyTimeout
+	Interval time.Duration // between polls; zero or less means DefaultMergeWhenReadyInterval
+	// OnPoll, if set, is called with the checks after every poll.
+	OnPoll func(*MergeResult)
+}
+
+// MergeWhenReady polls a pull request until the checks enforced by the
+// destination's branch restrictions pass, then merges it; advisory checks are
+// reported but not waited on. It gives up without merging when commits are
+// pushed to the source branch while waiting, a required build fails, the pull
+// request is closed, ctx is cancelled or the timeout expires. Unset Timeout
+// and Interval fall back to DefaultMergeWhenReadyTimeout and
+// DefaultMergeWhenReadyInterval.
+func (c *Client) MergeWhenReady(ctx context.Context, args MergeWhenReadyArgs) (*MergeResult, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
+		return nil, fmt.Errorf("workspace, repo_slug, and pr_id are required")
+	}
+	timeout, interval := args.Timeout, args.Interval
+	if timeout <= 0 {
+		timeout = DefaultMergeWhenReadyTimeout
+	}
+	if interval <= 0 {
+		interval = DefaultMergeWhenReadyInterval
+	}
+
+	deadline := time.Now().Add(timeout)
+	head := ""
+	for {
+		result, statuses, err := c.preflightMerge(args.MergePullRequestArgs)
+		if err != nil {
+			return nil, err
+		}
+
+		hash := endpointHash(result.PullRequest.Source)
+		if head == "" {
+			head = hash
+		} else if hash != head {
+			return result, fmt.Errorf("new commits were pushed while waiting (%s → %s); not merging", head, hash)
+		}
+		pending := result.Pending()
+		var failed []string
+		if slices.ContainsFunc(pending, func(c MergeCheck) bool { return c.Name == "builds" }) {
+			for _, s := range statuses {
+				if s.State == "FAILED" || s.State == "STOPPED" {
+					failed = append(failed, fmt.Sprintf("%s %s", s.Name, strings.ToLower(s.State)))
+				}
+			}
+		}
+		if len(failed) > 0 {
+			return result, fmt.Errorf("build failed: %s; not merging", strings.Join(failed, ", "))
+		}
+
+		if args.OnPoll != nil {
+			args.OnPoll(result)
+		}
+		if len(pending) == 0 {
+			pr, err := c.mergeAndWait(ctx, args.MergePullRequestArgs, result.Strategy)
+			if err != nil {
+				return result, err
+			}
+			result.PullRequest, result.Merged = pr, true
+			return result, nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return result, fmt.Errorf("timed out after %s %s", timeout, result.Summary())
+		}
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -3,6 +3,11 @@ package bitbucket
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 )
 
 type ListPullRequestsArgs struct {
@@ -12,6 +17,10 @@ type ListPullRequestsArgs struct {
 	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page"`
 	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
 	Query     string `json:"query,omitempty" jsonschema:"Filter query"`
+	All       bool   `json:"all,omitempty" jsonschema:"Fetch every page instead of a single page"`
+
+	// OnPage is called after each page when All is set.
+	OnPage func(fetched, total int) `json:"-"`
 }
 
 // ListPullRequests lists pull requests for a repository.
@@ -29,16 +38,24 @@ func (c *Client) ListPullRequests(args ListPullRequestsArgs) (*Paginated[PullReq
 		pagelen = 25
 	}
 	page := args.Page
-	if page == 0 {
+	if page == 0 || args.All {
 		page = 1
 	}
+	if args.All && args.Pagelen == 0 {
+		pagelen = 50
+	}
 
 	path := fmt.Sprintf("/repositories/%s/%s/pullrequests?state=%s&pagelen=%d&page=%d",
 		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), state, pagelen, page)
 	if args.Query != "" {
-		path += "&q=" + QueryEscape(args.Query)
+		// QueryEscape is a path escaper and would leave '&', '+' and '=' in
+		// branch names unescaped.
+		path += "&" + url.Values{"q": {args.Query}}.Encode()
 	}
 
+	if args.All {
+		return GetAllPaginated[PullRequest](c, path, args.OnPage)
+	}
 	return GetPaginated[PullRequest](c, path)
 }
 
@@ -59,14 +76,15 @@ func (c *Client) GetPullRequest(args GetPullRequestArgs) (*PullRequest, error) {
 }
 
 type CreatePullRequestArgs struct {
-	Workspace         string `json:"workspace" jsonschema:"Workspace slug"`
-	RepoSlug          string `json:"repo_slug" jsonschema:"Repository slug"`
-	Title             string `json:"title" jsonschema:"Title of the pull request"`
-	SourceBranch      string `json:"source_branch" jsonschema:"Source branch name"`
-	DestinationBranch string `json:"destination_branch,omitempty" jsonschema:"Destination branch name (optional, defaults to repo default)"`
-	Description       string `json:"description,omitempty" jsonschema:"Description of the pull request"`
-	CloseSourceBranch bool   `json:"close_source_branch,omitempty" jsonschema:"Close source branch on merge"`
-	Draft             bool   `json:"draft,omitempty" jsonschema:"Create as a draft PR"`
+	Workspace         string        `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug          string        `json:"repo_slug" jsonschema:"Repository slug"`
+	Title             string        `json:"title" jsonschema:"Title of the pull request"`
+	SourceBranch      string        `json:"source_branch" jsonschema:"Source branch name"`
+	DestinationBranch string        `json:"destination_branch,omitempty" jsonschema:"Destination branch name (optional, defaults to repo default)"`
+	Description       string        `json:"description,omitempty" jsonschema:"Description of the pull request"`
+	CloseSourceBranch bool          `json:"close_source_branch,omitempty" jsonschema:"Close source branch on merge"`
+	Draft             bool          `json:"draft,omitempty" jsonschema:"Create as a draft PR"`
+	Reviewers         []ReviewerRef `json:"-"`
 }
 
 // CreatePullRequest creates a new pull request.
@@ -85,6 +103,7 @@ func (c *Client) CreatePullRequest(args CreatePullRequestArgs) (*PullRequest, er
 		},
 		Description:       args.Description,
 		CloseSourceBranch: args.CloseSourceBranch,
+		Reviewers:         args.Reviewers,
 		Draft:             args.Draft,
 	}
 
@@ -117,6 +136,10 @@ type UpdatePullRequestArgs struct {
 	PRID        int     `json:"pr_id" jsonschema:"Pull request ID"`
 	Title       *string `json:"title,omitempty" jsonschema:"New title for the pull request"`
 	Description *string `json:"description,omitempty" jsonschema:"New description for the pull request"`
+	// Destination, when set, retargets the pull request onto another branch.
+	Destination *string `json:"-"`
+	// Reviewers, when set, replaces the reviewer list.
+	Reviewers *[]ReviewerRef `json:"-"`
 }
 
 // UpdatePullRequest updates an existing pull request.
@@ -132,52 +155,17 @@ func (c *Client) UpdatePullRequest(args UpdatePullRequestArgs) (*PullRequest, er
 	if args.Description != nil {
 		body["description"] = *args.Description
 	}
-
-	respData, err := c.Put(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d",
-		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID), body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update pull request: %v", err)
-	}
-
-	var pr PullRequest
-	if err := json.Unmarshal(respData, &pr); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
-	}
-
-	return &pr, nil
-}
-
-type MergePullRequestArgs struct {
-	Workspace         string `json:"workspace" jsonschema:"Workspace slug"`
-	RepoSlug          string `json:"repo_slug" jsonschema:"Repository slug"`
-	PRID              int    `json:"pr_id" jsonschema:"Pull request ID"`
-	CloseSourceBranch bool   `json:"close_source_branch,omitempty" jsonschema:"Close source branch"`
-	MergeStrategy     string `json:"merge_strategy,omitempty" jsonschema:"Merge strategy (e.g. merge_commit, squash, fast_forward, default: merge_commit)"`
-	Message           string `json:"message,omitempty" jsonschema:"Commit message"`
-}
-
-// MergePullRequest merges a pull request.
-func (c *Client) MergePullRequest(args MergePullRequestArgs) (*PullRequest, error) {
-	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
-		return nil, fmt.Errorf("workspace, repo_slug, and pr_id are required")
-	}
-
-	strategy := args.MergeStrategy
-	if strategy == "" {
-		strategy = "merge_commit"
+	if args.Destination != nil {
+		body["destination"] = map[string]any{"branch": map[string]string{"name": *args.Destination}}
 	}
-
-	body := MergePRRequest{
-		Type:              "pullrequest",
-		CloseSourceBranch: args.CloseSourceBranch,
-		MergeStrategy:     strategy,
-		Message:           args.Message,
+	if args.Reviewers != nil {
+		body["reviewers"] = *args.Reviewers
 	}
 
-	respData, err := c.Post(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/merge",
+	respData, err := c.Put(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d",
 		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID), body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to merge pull request: %v", err)
+		return nil, fmt.Errorf("failed to update pull request: %v", err)
 	}
 
 	var pr PullRequest
@@ -215,6 +203,57 @@ func (c *Client) UnapprovePullRequest(args PullRequestActionArgs) error {
 		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID))
 }
 
+// RequestChangesPullRequest marks a pull request as needing changes.
+func (c *Client) RequestChangesPullRequest(args PullRequestActionArgs) error {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
+		return fmt.Errorf("workspace, repo_slug, and pr_id are required")
+	}
+
+	_, err := c.Post(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/request-changes",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID), map[string]interface{}{})
+	return err
+}
+
+// RemoveRequestChangesPullRequest withdraws a change request from a pull request.
+func (c *Client) RemoveRequestChangesPullRequest(args PullRequestActionArgs) error {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
+		return fmt.Errorf("workspace, repo_slug, and pr_id are required")
+	}
+
+	return c.Delete(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/request-changes",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID))
+}
+
+// ChangeReviewers returns the reviewer list of a pull request whose current
+// reviewers are current, after adding add and removing remove.
+func ChangeReviewers(current []User, add, remove []ReviewerRef) []ReviewerRef {
+	out := []ReviewerRef{}
+	keep := func(u User) {
+		for _, r := range remove {
+			if r.Matches(u) {
+				return
+			}
+		}
+		out = append(out, ReviewerRef{UUID: u.UUID, AccountID: u.AccountID})
+	}
+	for _, u := range current {
+		keep(u)
+	}
+	for _, a := range add {
+		dup := false
+		for _, u := range current {
+			if a.Matches(u) {
+				dup = true
+				break
+			}
+		}
+		if !dup && !slices.Contains(out, a) {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
 // DeclinePullRequest declines a pull request.
 func (c *Client) DeclinePullRequest(args PullRequestActionArgs) error {
 	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
@@ -227,14 +266,22 @@ func (c *Client) DeclinePullRequest(args PullRequestActionArgs) error {
 }
 
 // GetPRDiff gets the diff for a pull request.
-func (c *Client) GetPRDiff(args PullRequestActionArgs) ([]byte, error) {
+func (c *Client) GetPRDiff(args PullRequestActionArgs, opts DiffOptions) ([]byte, error) {
 	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
 		return nil, fmt.Errorf("workspace, repo_slug, and pr_id are required")
 	}
 
-	raw, _, err := c.GetRaw(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/diff",
-		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID))
-	return raw, err
+	endpoint := fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/diff",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID)
+	if q := opts.query(); len(q) > 0 {
+		endpoint += "?" + q.Encode()
+	}
+
+	raw, _, err := c.GetRaw(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return opts.filter(raw), nil
 }
 
 // GetPRDiffStat gets the diffstat for a pull request.
@@ -256,3 +303,94 @@ func (c *Client) ListPRCommits(args PullRequestActionArgs) (*Paginated[Commit],
 	return GetPaginated[Commit](c, fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/commits",
 		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID))
 }
+
+// ListPRStatuses lists the build and other commit statuses of a pull request.
+func (c *Client) ListPRStatuses(args PullRequestActionArgs) (*Paginated[CommitStatus], error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
+		return nil, fmt.Errorf("workspace, repo_slug, and pr_id are required")
+	}
+
+	return GetAllPaginated[CommitStatus](c, fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/statuses?pagelen=100",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID), nil)
+}
+
+// PullRequestTemplatePaths are the repository files checked, in order, for a
+// pull request description template.
+var PullRequestTemplatePaths = []string{
+	".bitbucket/PULL_REQUEST_TEMPLATE.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+	".github/pull_request_template.md",
+}
+
+// GetPullRequestTemplate returns the first pull request template found at ref,
+// or "" when the repository has none.
+func (c *Client) GetPullRequestTemplate(workspace, repoSlug, ref string) (string, error) {
+	for _, path := range PullRequestTemplatePaths {
+		raw, _, err := c.GetFileContent(GetFileContentArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			Path:      path,
+			Ref:       ref,
+		})
+		if err != nil {
+			if strings.HasPrefix(err.Error(), "API error 404") {
+				continue
+			}
+			return "", err
+		}
+		return string(raw), nil
+	}
+	return "", nil
+}
+
+// LocalPullRequestTemplatePath is the user's own pull request template, used
+// when the repository has none.
+func LocalPullRequestTemplatePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "bbkt", "pull_request_template.md"), nil
+}
+
+// ListDefaultReviewers lists the repository's effective default reviewers,
+// including those inherited from its project. Pull requests created through
+// the API don't get them automatically.
+func (c *Client) ListDefaultReviewers(workspace, repoSlug string) ([]User, error) {
+	if workspace == "" || repoSlug == "" {
+		return nil, fmt.Errorf("workspace and repo_slug are required")
+	}
+
+	result, err := GetAllPaginated[DefaultReviewer](c, fmt.Sprintf("/repositories/%s/%s/effective-default-reviewers?pagelen=100",
+		QueryEscape(workspace), QueryEscape(repoSlug)), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]User, 0, len(result.Values))
+	for _, r := range result.Values {
+		if r.User != nil {
+			users = append(users, *r.User)
+		}
+	}
+	return users, nil
+}
+
+// DefaultPRTitle proposes a title for a pull request from branch: the summary
+// of its only commit, or else the branch name made readable, so that
+// "feature/add-login" becomes "Add login".
+func DefaultPRTitle(branch string, commits []Commit) string {
+	if len(commits) == 1 {
+		summary, _, _ := strings.Cut(strings.TrimSpace(commits[0].Message), "\n")
+		if summary = strings.TrimSpace(summary); summary != "" {
+			return summary
+		}
+	}
+	name := branch[strings.LastIndex(branch, "/")+1:]
+	name = strings.TrimSpace(strings.NewReplacer("-", " ", "_", " ").Replace(name))
+	if name == "" {
+		return branch
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
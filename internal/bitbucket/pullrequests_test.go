@@ -0,0 +1,68 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestChangeReviewers(t *testing.T) {
+	alice := User{UUID: "{alice}", AccountID: "1"}
+	bob := User{UUID: "{bob}", AccountID: "2"}
+
+	tests := []struct {
+		name        string
+		current     []User
+		add, remove []ReviewerRef
+		want        []ReviewerRef
+	}{
+		{"no change", []User{alice}, nil, nil, []ReviewerRef{{UUID: "{alice}", AccountID: "1"}}},
+		{"add", []User{alice}, []ReviewerRef{{AccountID: "2"}}, nil,
+			[]ReviewerRef{{UUID: "{alice}", AccountID: "1"}, {AccountID: "2"}}},
+		{"add existing reviewer", []User{alice}, []ReviewerRef{{AccountID: "1"}}, nil,
+			[]ReviewerRef{{UUID: "{alice}", AccountID: "1"}}},
+		{"add twice", nil, []ReviewerRef{{UUID: "{bob}"}, {UUID: "{bob}"}}, nil, []ReviewerRef{{UUID: "{bob}"}}},
+		{"remove by uuid", []User{alice, bob}, nil, []ReviewerRef{{UUID: "{alice}"}},
+			[]ReviewerRef{{UUID: "{bob}", AccountID: "2"}}},
+		{"remove last reviewer", []User{alice}, nil, []ReviewerRef{{AccountID: "1"}}, []ReviewerRef{}},
+		{"no reviewers", nil, nil, nil, []ReviewerRef{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChangeReviewers(tt.current, tt.add, tt.remove)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ChangeReviewers() = %v, want %v", got, tt.want)
+			}
+			// Removing the last reviewer has to send [], not null.
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) == "null" {
+				t.Errorf("ChangeReviewers() marshals to null")
+			}
+		})
+	}
+}
+
+func TestListPullRequestsQueryEscaping(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.Query().Get("q")
+		_, _ = w.Write([]byte(`{"values":[]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient("", "", "token")
+	c.baseURL = srv.URL
+	query := `source.branch.name = "fix/a&b+c=d"`
+	if _, err := c.ListPullRequests(ListPullRequestsArgs{Workspace: "acme", RepoSlug: "api", Query: query}); err != nil {
+		t.Fatal(err)
+	}
+	if got != query {
+		t.Errorf("server got q=%q, want %q", got, query)
+	}
+}
@@ -12,6 +12,10 @@ type ListRepositoriesArgs struct {
 	Query     string `json:"query,omitempty" jsonschema:"Bitbucket query filter (e.g. name~'myrepo')"`
 	Role      string `json:"role,omitempty" jsonschema:"Filter by role: owner, admin, contributor, member"`
 	Sort      string `json:"sort,omitempty" jsonschema:"Sort field (e.g. -updated_on)"`
+	All       bool   `json:"all,omitempty" jsonschema:"Fetch every page instead of a single page"`
+
+	// OnPage is called after each page when All is set.
+	OnPage func(fetched, total int) `json:"-"`
 }
 
 // ListRepositories lists repositories in a workspace.
@@ -25,9 +29,12 @@ func (c *Client) ListRepositories(args ListRepositoriesArgs) (*Paginated[Reposit
 		pagelen = 25
 	}
 	page := args.Page
-	if page == 0 {
+	if page == 0 || args.All {
 		page = 1
 	}
+	if args.All && args.Pagelen == 0 {
+		pagelen = 100
+	}
 
 	path := fmt.Sprintf("/repositories/%s?pagelen=%d&page=%d", QueryEscape(args.Workspace), pagelen, page)
 	if args.Query != "" {
@@ -40,6 +47,9 @@ func (c *Client) ListRepositories(args ListRepositoriesArgs) (*Paginated[Reposit
 		path += "&sort=" + QueryEscape(args.Sort)
 	}
 
+	if args.All {
+		return GetAllPaginated[Repository](c, path, args.OnPage)
+	}
 	return GetPaginated[Repository](c, path)
 }
 
@@ -3,6 +3,7 @@ package bitbucket
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 type GetFileContentArgs struct {
@@ -147,10 +148,11 @@ type WriteFileArgs struct {
 	Author    string `json:"author,omitempty" jsonschema:"Commit author in 'Name <email>' format"`
 }
 
-// WriteFile writes or updates a file in the repository.
-func (c *Client) WriteFile(args WriteFileArgs) error {
+// WriteFile writes or updates a file in the repository and returns the
+// commit it made.
+func (c *Client) WriteFile(args WriteFileArgs) (*Commit, error) {
 	if args.Workspace == "" || args.RepoSlug == "" || args.Path == "" {
-		return fmt.Errorf("workspace, repo_slug, and path are required")
+		return nil, fmt.Errorf("workspace, repo_slug, and path are required")
 	}
 
 	endpoint := fmt.Sprintf("/repositories/%s/%s/src",
@@ -171,12 +173,12 @@ func (c *Client) WriteFile(args WriteFileArgs) error {
 		args.Path: []byte(args.Content),
 	}
 
-	_, err := c.PostMultipart(endpoint, fields, files)
+	_, location, err := c.PostMultipart(endpoint, fields, files)
 	if err != nil {
-		return fmt.Errorf("writing file: %w", err)
+		return nil, fmt.Errorf("writing file: %w", err)
 	}
 
-	return nil
+	return c.srcCommit(args.Workspace, args.RepoSlug, location), nil
 }
 
 type DeleteFileArgs struct {
@@ -188,10 +190,10 @@ type DeleteFileArgs struct {
 	Author    string `json:"author,omitempty" jsonschema:"Commit author in 'Name <email>' format"`
 }
 
-// DeleteFile deletes a file from the repository.
-func (c *Client) DeleteFile(args DeleteFileArgs) error {
+// DeleteFile deletes a file from the repository and returns the commit it made.
+func (c *Client) DeleteFile(args DeleteFileArgs) (*Commit, error) {
 	if args.Workspace == "" || args.RepoSlug == "" || args.Path == "" {
-		return fmt.Errorf("workspace, repo_slug, and path are required")
+		return nil, fmt.Errorf("workspace, repo_slug, and path are required")
 	}
 
 	endpoint := fmt.Sprintf("/repositories/%s/%s/src",
@@ -212,10 +214,26 @@ func (c *Client) DeleteFile(args DeleteFileArgs) error {
 	// However, we just send it as a regular text field
 	fields["files"] = args.Path
 
-	_, err := c.PostMultipart(endpoint, fields, nil)
+	_, location, err := c.PostMultipart(endpoint, fields, nil)
 	if err != nil {
-		return fmt.Errorf("deleting file: %w", err)
+		return nil, fmt.Errorf("deleting file: %w", err)
 	}
 
-	return nil
+	return c.srcCommit(args.Workspace, args.RepoSlug, location), nil
+}
+
+// srcCommit looks up the commit a /src POST made from the Location header of
+// its response. The file is already committed by then, so a failed lookup
+// still returns the hash, and nil is returned only when there is no Location.
+func (c *Client) srcCommit(workspace, repoSlug, location string) *Commit {
+	_, hash, ok := strings.Cut(location, "/commit/")
+	hash = strings.Trim(hash, "/")
+	if !ok || hash == "" {
+		return nil
+	}
+	commit, err := c.GetCommit(GetCommitArgs{Workspace: workspace, RepoSlug: repoSlug, Commit: hash})
+	if err != nil {
+		return &Commit{Hash: hash}
+	}
+	return commit
 }
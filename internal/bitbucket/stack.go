@@ -0,0 +1,310 @@
+package bitbucket
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxStackRetargets bounds how many merged pull requests SyncStack follows
+// below the bottom of a stack.
+const maxStackRetargets = 10
+
+// Stack is a chain of pull requests where each one targets the source branch
+// of the one below it. Stacks are not stored anywhere; they are read back from
+// the source and destination branches of the open pull requests.
+type Stack struct {
+	Base         string        `json:"base"`
+	PullRequests []PullRequest `json:"pull_requests"` // bottom first
+}
+
+// Branches returns the source branches of the stack, bottom first.
+func (s *Stack) Branches() []string {
+	branches := make([]string, len(s.PullRequests))
+	for i, pr := range s.PullRequests {
+		branches[i] = endpointBranch(pr.Source)
+	}
+	return branches
+}
+
+// buildStack finds the stack containing branch among open pull requests. It
+// follows destinations down from branch to the base, a branch with no open
+// pull request of its own, and sources up to the top. When several pull
+// requests target the same branch, the oldest one is followed.
+func buildStack(prs []PullRequest, branch string) *Stack {
+	bySource := map[string]PullRequest{}
+	byDest := map[string][]PullRequest{}
+	for _, pr := range prs {
+		src := endpointBranch(pr.Source)
+		if src == "" {
+			continue
+		}
+		bySource[src] = pr
+		dest := endpointBranch(pr.Destination)
+		byDest[dest] = append(byDest[dest], pr)
+	}
+	for _, children := range byDest {
+		slices.SortFunc(children, func(a, b PullRequest) int { return a.CreatedOn.Compare(b.CreatedOn) })
+	}
+
+	if _, ok := bySource[branch]; !ok {
+		return &Stack{Base: branch}
+	}
+
+	seen := map[string]bool{}
+	var below []PullRequest
+	cur := branch
+	for {
+		pr, ok := bySource[cur]
+		if !ok || seen[cur] {
+			break
+		}
+		seen[cur] = true
+		below = append(below, pr)
+		cur = endpointBranch(pr.Destination)
+	}
+
+	stack := &Stack{Base: cur}
+	for _, pr := range slices.Backward(below) {
+		stack.PullRequests = append(stack.PullRequests, pr)
+	}
+	for cur = branch; len(byDest[cur]) > 0; {
+		next := byDest[cur][0]
+		src := endpointBranch(next.Source)
+		if seen[src] {
+			break
+		}
+		seen[src] = true
+		stack.PullRequests = append(stack.PullRequests, next)
+		cur = src
+	}
+	return stack
+}
+
+type StackArgs struct {
+	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
+	Branch    string `json:"branch" jsonschema:"Any branch in the stack"`
+}
+
+// GetStack returns the stack of open pull requests that branch belongs to.
+func (c *Client) GetStack(args StackArgs) (*Stack, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.Branch == "" {
+		return nil, fmt.Errorf("workspace, repo_slug, and branch are required")
+	}
+
+	open, err := c.ListPullRequests(ListPullRequestsArgs{Workspace: args.Workspace, RepoSlug: args.RepoSlug, All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %v", err)
+	}
+
+	stack := buildStack(open.Values, args.Branch)
+	if len(stack.PullRequests) == 0 {
+		return nil, fmt.Errorf("%s has no open pull request", args.Branch)
+	}
+	return stack, nil
+}
+
+type CreateStackArgs struct {
+	Workspace string   `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string   `json:"repo_slug" jsonschema:"Repository slug"`
+	Branches  []string `json:"branches" jsonschema:"Branches of the stack, bottom first"`
+	Base      string   `json:"base,omitempty" jsonschema:"Branch the bottom pull request targets (defaults to the repository's main branch)"`
+	Draft     bool     `json:"draft,omitempty" jsonschema:"Create the pull requests as drafts"`
+}
+
+// CreateStack opens a pull request for each branch onto the one before it, and
+// for the first onto Base. A branch that already has an open pull request keeps
+// it, retargeted when it points elsewhere, so the stack can be re-created after
+// adding a branch on top. New pull requests are titled from their commits and
+// never close their source branch on merge.
+//
+// On error, the returned stack holds the pull requests handled so far.
+func (c *Client) CreateStack(args CreateStackArgs) (*Stack, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || len(args.Branches) == 0 {
+		return nil, fmt.Errorf("workspace, repo_slug, and branches are required")
+	}
+	for i, branch := range args.Branches {
+		if branch == "" || slices.Contains(args.Branches[:i], branch) || branch == args.Base {
+			return nil, fmt.Errorf("invalid stack: branch '%s' is empty, listed twice or the base", branch)
+		}
+	}
+
+	base := args.Base
+	if base == "" {
+		repo, err := c.GetRepository(GetRepositoryArgs{Workspace: args.Workspace, RepoSlug: args.RepoSlug})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve base branch: %v", err)
+		}
+		if repo.MainBranch == nil || repo.MainBranch.Name == "" {
+			return nil, fmt.Errorf("repository has no main branch; pass a base branch")
+		}
+		base = repo.MainBranch.Name
+	}
+
+	open, err := c.ListPullRequests(ListPullRequestsArgs{Workspace: args.Workspace, RepoSlug: args.RepoSlug, All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull requests: %v", err)
+	}
+	existing := map[string]PullRequest{}
+	for _, pr := range open.Values {
+		existing[endpointBranch(pr.Source)] = pr
+	}
+
+	stack := &Stack{Base: base}
+	dest := base
+	for _, branch := range args.Branches {
+		pr, ok := existing[branch]
+		switch {
+		case ok && endpointBranch(pr.Destination) != dest:
+			target := dest
+			updated, err := c.UpdatePullRequest(UpdatePullRequestArgs{
+				Workspace:   args.Workspace,
+				RepoSlug:    args.RepoSlug,
+				PRID:        pr.ID,
+				Destination: &target,
+			})
+			if err != nil {
+				return stack, fmt.Errorf("failed to retarget #%d onto %s: %v", pr.ID, dest, err)
+			}
+			pr = *updated
+		case !ok:
+			commits, err := c.ListCommits(ListCommitsArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				Include:   branch,
+				Exclude:   dest,
+				Pagelen:   2,
+			})
+			if err != nil {
+				return stack, fmt.Errorf("failed to list commits of %s: %v", branch, err)
+			}
+			var description string
+			if n := len(stack.PullRequests); n > 0 {
+				description = fmt.Sprintf("Stacked on #%d.", stack.PullRequests[n-1].ID)
+			}
+			created, err := c.CreatePullRequest(CreatePullRequestArgs{
+				Workspace:         args.Workspace,
+				RepoSlug:          args.RepoSlug,
+				Title:             DefaultPRTitle(branch, commits.Values),
+				SourceBranch:      branch,
+				DestinationBranch: dest,
+				Description:       description,
+				Draft:             args.Draft,
+			})
+			if err != nil {
+				return stack, fmt.Errorf("failed to create pull request for %s: %v", branch, err)
+			}
+			pr = *created
+		}
+		stack.PullRequests = append(stack.PullRequests, pr)
+		dest = branch
+	}
+	return stack, nil
+}
+
+// StackRetarget records a pull request moved onto a new destination because
+// the pull request it was stacked on merged.
+type StackRetarget struct {
+	PullRequest PullRequest `json:"pull_request"`
+	Merged      PullRequest `json:"merged"`
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+}
+
+// SyncStack retargets the bottom of branch's stack once the pull request it
+// was stacked on has merged, onto the branch that one merged into, and repeats
+// while that branch was itself merged. A merge only counts if it happened
+// after the bottom pull request was opened, and a stack based on the
+// repository's main branch is never moved.
+//
+// It returns the stack as it is afterwards and the retargets made, oldest first.
+func (c *Client) SyncStack(args StackArgs) (*Stack, []StackRetarget, error) {
+	stack, err := c.GetStack(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repo, err := c.GetRepository(GetRepositoryArgs{Workspace: args.Workspace, RepoSlug: args.RepoSlug})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get repository: %v", err)
+	}
+	var mainBranch string
+	if repo.MainBranch != nil {
+		mainBranch = repo.MainBranch.Name
+	}
+
+	bottom := &stack.PullRequests[0]
+	var retargets []StackRetarget
+	for range maxStackRetargets {
+		if stack.Base == mainBranch {
+			break
+		}
+		merged, err := c.lastMergedFrom(args.Workspace, args.RepoSlug, stack.Base)
+		if err != nil {
+			return stack, retargets, err
+		}
+		if merged == nil || merged.UpdatedOn.Before(bottom.CreatedOn) {
+			break
+		}
+
+		to := endpointBranch(merged.Destination)
+		updated, err := c.UpdatePullRequest(UpdatePullRequestArgs{
+			Workspace:   args.Workspace,
+			RepoSlug:    args.RepoSlug,
+			PRID:        bottom.ID,
+			Destination: &to,
+		})
+		if err != nil {
+			return stack, retargets, fmt.Errorf("failed to retarget #%d onto %s: %v", bottom.ID, to, err)
+		}
+		retargets = append(retargets, StackRetarget{PullRequest: *updated, Merged: *merged, From: stack.Base, To: to})
+		*bottom = *updated
+		stack.Base = to
+	}
+	return stack, retargets, nil
+}
+
+// lastMergedFrom returns the most recently merged pull request from branch, or
+// nil if none has merged.
+func (c *Client) lastMergedFrom(workspace, repoSlug, branch string) (*PullRequest, error) {
+	result, err := c.ListPullRequests(ListPullRequestsArgs{
+		Workspace: workspace,
+		RepoSlug:  repoSlug,
+		State:     "MERGED",
+		Query:     "source.branch.name = " + bbqlString(branch),
+		Pagelen:   50,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merged pull requests from %s: %v", branch, err)
+	}
+
+	var last *PullRequest
+	for i, pr := range result.Values {
+		if last == nil || pr.UpdatedOn.After(last.UpdatedOn) {
+			last = &result.Values[i]
+		}
+	}
+	return last, nil
+}
+
+// RebaseNotes returns the git commands that move the stack's branches onto
+// the branch its bottom was retargeted to, dropping the commits that already
+// landed there through the merged pull request.
+func RebaseNotes(stack *Stack, retargets []StackRetarget) string {
+	if len(stack.PullRequests) == 0 || len(retargets) == 0 {
+		return ""
+	}
+	first, last := retargets[0], retargets[len(retargets)-1]
+	upstream := endpointHash(first.Merged.Source)
+	if upstream == "" {
+		upstream = "origin/" + first.From
+	}
+	branches := stack.Branches()
+
+	var b strings.Builder
+	b.WriteString("git fetch origin\n")
+	fmt.Fprintf(&b, "git rebase --update-refs --onto origin/%s %s %s\n", last.To, upstream, branches[len(branches)-1])
+	fmt.Fprintf(&b, "git push --force-with-lease origin %s\n", strings.Join(branches, " "))
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package bitbucket
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// stackPR is an open pull request from src onto dest, opened id hours after
+// a fixed time so lower IDs are older.
+func stackPR(id int, src, dest string) PullRequest {
+	pr := PullRequest{
+		ID:          id,
+		CreatedOn:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(id) * time.Hour),
+		Destination: PREndpoint{Branch: &Branch{Name: dest}},
+	}
+	if src != "" {
+		pr.Source = PREndpoint{Branch: &Branch{Name: src}}
+	}
+	return pr
+}
+
+func TestBuildStack(t *testing.T) {
+	linear := []PullRequest{stackPR(3, "c", "b"), stackPR(1, "a", "main"), stackPR(2, "b", "a"), stackPR(9, "other", "main")}
+	// x and b both target a; b is older, so it continues the stack from a.
+	fork := []PullRequest{stackPR(4, "x", "a"), stackPR(1, "a", "main"), stackPR(2, "b", "a"), stackPR(3, "c", "b")}
+	cycle := []PullRequest{stackPR(1, "a", "b"), stackPR(2, "b", "a")}
+
+	tests := []struct {
+		name   string
+		prs    []PullRequest
+		branch string
+		base   string
+		ids    []int
+	}{
+		{"from the bottom", linear, "a", "main", []int{1, 2, 3}},
+		{"from the middle", linear, "b", "main", []int{1, 2, 3}},
+		{"from the top", linear, "c", "main", []int{1, 2, 3}},
+		{"single pull request", linear, "other", "main", []int{9}},
+		{"base branch", linear, "main", "main", nil},
+		{"unknown branch", linear, "nope", "nope", nil},
+		{"no pull requests", nil, "a", "a", nil},
+		{"fork follows the oldest child", fork, "a", "main", []int{1, 2, 3}},
+		{"newer fork from its own branch", fork, "x", "main", []int{1, 4}},
+		{"cycle", cycle, "a", "a", []int{2, 1}},
+		{"pull request without source branch", []PullRequest{stackPR(5, "", "main"), stackPR(1, "a", "main")}, "a", "main", []int{1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stack := buildStack(tt.prs, tt.branch)
+			var ids []int
+			for _, pr := range stack.PullRequests {
+				ids = append(ids, pr.ID)
+			}
+			if stack.Base != tt.base || !slices.Equal(ids, tt.ids) {
+				t.Errorf("buildStack(%q) = base %q, %v; want base %q, %v", tt.branch, stack.Base, ids, tt.base, tt.ids)
+			}
+		})
+	}
+}
+
+func TestRebaseNotes(t *testing.T) {
+	stack := &Stack{Base: "main", PullRequests: []PullRequest{stackPR(2, "b", "main"), stackPR(3, "c", "b")}}
+	merged := stackPR(1, "a", "main")
+	merged.Source.Commit = &Commit{Hash: "0123456789abcdef"}
+	unhashed := stackPR(1, "a", "release")
+
+	tests := []struct {
+		name      string
+		stack     *Stack
+		retargets []StackRetarget
+		want      string
+	}{
+		{"nothing retargeted", stack, nil, ""},
+		{"empty stack", &Stack{Base: "main"}, []StackRetarget{{Merged: merged, From: "a", To: "main"}}, ""},
+		{"one retarget", stack, []StackRetarget{{Merged: merged, From: "a", To: "main"}},
+			"git fetch origin\n" +
+				"git rebase --update-refs --onto origin/main 0123456789ab c\n" +
+				"git push --force-with-lease origin b c\n"},
+		{"merged commit unknown", stack, []StackRetarget{{Merged: unhashed, From: "a", To: "release"}},
+			"git fetch origin\n" +
+				"git rebase --update-refs --onto origin/release origin/a c\n" +
+				"git push --force-with-lease origin b c\n"},
+		{"several retargets rebase from the first onto the last", stack, []StackRetarget{
+			{Merged: merged, From: "a", To: "release"},
+			{Merged: unhashed, From: "release", To: "main"},
+		}, "git fetch origin\n" +
+			"git rebase --update-refs --onto origin/main 0123456789ab c\n" +
+			"git push --force-with-lease origin b c\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RebaseNotes(tt.stack, tt.retargets); got != tt.want {
+				t.Errorf("RebaseNotes() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBBQLString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"feature/x", `"feature/x"`},
+		{`a"b`, `"a\"b"`},
+		{`a\b`, `"a\\b"`},
+		{`x\" OR state = "OPEN`, `"x\\\" OR state = \"OPEN"`},
+	}
+	for _, tt := range tests {
+		if got := bbqlString(tt.in); got != tt.want {
+			t.Errorf("bbqlString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Build status states.
+var CommitStatusStates = []string{"SUCCESSFUL", "FAILED", "INPROGRESS", "STOPPED"}
+
+type ListCommitStatusesArgs struct {
+	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
+	Commit    string `json:"commit" jsonschema:"Commit hash"`
+	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page (default 50)"`
+	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
+}
+
+// ListCommitStatuses lists the build statuses reported for a commit.
+func (c *Client) ListCommitStatuses(args ListCommitStatusesArgs) (*Paginated[CommitStatus], error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.Commit == "" {
+		return nil, fmt.Errorf("workspace, repo_slug, and commit are required")
+	}
+
+	pagelen := args.Pagelen
+	if pagelen == 0 {
+		pagelen = 50
+	}
+	page := args.Page
+	if page == 0 {
+		page = 1
+	}
+
+	return GetPaginated[CommitStatus](c, fmt.Sprintf("/repositories/%s/%s/commit/%s/statuses?pagelen=%d&page=%d",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), QueryEscape(args.Commit), pagelen, page))
+}
+
+type CommitStatusArgs struct {
+	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
+	Commit    string `json:"commit" jsonschema:"Commit hash"`
+	Key       string `json:"key" jsonschema:"Build status key"`
+}
+
+// GetCommitStatus gets the build status with the given key on a commit.
+func (c *Client) GetCommitStatus(args CommitStatusArgs) (*CommitStatus, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.Commit == "" || args.Key == "" {
+		return nil, fmt.Errorf("workspace, repo_slug, commit, and key are required")
+	}
+
+	return GetJSON[CommitStatus](c, fmt.Sprintf("/repositories/%s/%s/commit/%s/statuses/build/%s",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), QueryEscape(args.Commit), QueryEscape(args.Key)))
+}
+
+type SetCommitStatusArgs struct {
+	Workspace   string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug    string `json:"repo_slug" jsonschema:"Repository slug"`
+	Commit      string `json:"commit" jsonschema:"Commit hash"`
+	Key         string `json:"key" jsonschema:"Build status key, unique per commit (e.g. the CI job name)"`
+	State       string `json:"state" jsonschema:"SUCCESSFUL, FAILED, INPROGRESS or STOPPED"`
+	URL         string `json:"url,omitempty" jsonschema:"Link to the build (required when creating)"`
+	Name        string `json:"name,omitempty" jsonschema:"Display name"`
+	Description string `json:"description,omitempty" jsonschema:"Short description of the result"`
+	Refname     string `json:"refname,omitempty" jsonschema:"Branch or tag the build ran for"`
+}
+
+func (args SetCommitStatusArgs) body() (map[string]any, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.Commit == "" || args.Key == "" {
+		return nil, fmt.Errorf("workspace, repo_slug, commit, and key are required")
+	}
+	body := map[string]any{"key": args.Key}
+	if args.State != "" {
+		state := strings.ToUpper(args.State)
+		if !slices.Contains(CommitStatusStates, state) {
+			return nil, fmt.Errorf("invalid state '%s' (expected %s)", args.State, strings.Join(CommitStatusStates, ", "))
+		}
+		body["state"] = state
+	}
+	for k, v := range map[string]string{"url": args.URL, "name": args.Name, "description": args.Description, "refname": args.Refname} {
+		if v != "" {
+			body[k] = v
+		}
+	}
+	return body, nil
+}
+
+// CreateCommitStatus reports a new build status on a commit.
+func (c *Client) CreateCommitStatus(args SetCommitStatusArgs) (*CommitStatus, error) {
+	body, err := args.body()
+	if err != nil {
+		return nil, err
+	}
+	if args.State == "" || args.URL == "" {
+		return nil, fmt.Errorf("state and url are required")
+	}
+
+	respData, err := c.Post(fmt.Sprintf("/repositories/%s/%s/commit/%s/statuses/build",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), QueryEscape(args.Commit)), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create commit status: %v", err)
+	}
+
+	var status CommitStatus
+	if err := json.Unmarshal(respData, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	return &status, nil
+}
+
+// UpdateCommitStatus changes an existing build status; empty fields keep
+// their current value.
+func (c *Client) UpdateCommitStatus(args SetCommitStatusArgs) (*CommitStatus, error) {
+	body, err := args.body()
+	if err != nil {
+		return nil, err
+	}
+
+	respData, err := c.Put(fmt.Sprintf("/repositories/%s/%s/commit/%s/statuses/build/%s",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), QueryEscape(args.Commit), QueryEscape(args.Key)), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update commit status: %v", err)
+	}
+
+	var status CommitStatus
+	if err := json.Unmarshal(respData, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	return &status, nil
+}
+
+// SetCommitStatus updates the build status with args.Key, creating it if the
+// commit doesn't have one yet, so CI jobs can report every stage the same way.
+func (c *Client) SetCommitStatus(args SetCommitStatusArgs) (*CommitStatus, error) {
+	status, err := c.UpdateCommitStatus(args)
+	if err != nil && strings.Contains(err.Error(), "API error 404") {
+		return c.CreateCommitStatus(args)
+	}
+	return status, err
+}
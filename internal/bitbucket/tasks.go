@@ -0,0 +1,151 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	TaskStateResolved   = "RESOLVED"
+	TaskStateUnresolved = "UNRESOLVED"
+)
+
+type ListPRTasksArgs struct {
+	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
+	PRID      int    `json:"pr_id" jsonschema:"Pull request ID"`
+	State     string `json:"state,omitempty" jsonschema:"Filter by state (RESOLVED, UNRESOLVED)"`
+	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page (default 50)"`
+	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
+	All       bool   `json:"all,omitempty" jsonschema:"Fetch every page instead of a single page"`
+}
+
+// ListPRTasks lists tasks on a pull request.
+func (c *Client) ListPRTasks(args ListPRTasksArgs) (*Paginated[PRTask], error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 {
+		return nil, fmt.Errorf("workspace, repo_slug, and pr_id are required")
+	}
+
+	pagelen := args.Pagelen
+	if pagelen == 0 {
+		pagelen = 50
+	}
+	page := args.Page
+	if page == 0 || args.All {
+		page = 1
+	}
+
+	path := fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/tasks?pagelen=%d&page=%d",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID, pagelen, page)
+	if args.State != "" {
+		path += "&q=" + QueryEscape(fmt.Sprintf(`state="%s"`, args.State))
+	}
+
+	if args.All {
+		return GetAllPaginated[PRTask](c, path, nil)
+	}
+	return GetPaginated[PRTask](c, path)
+}
+
+type PRTaskArgs struct {
+	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
+	PRID      int    `json:"pr_id" jsonschema:"Pull request ID"`
+	TaskID    int    `json:"task_id" jsonschema:"Task ID"`
+}
+
+// GetPRTask gets a single pull request task.
+func (c *Client) GetPRTask(args PRTaskArgs) (*PRTask, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 || args.TaskID == 0 {
+		return nil, fmt.Errorf("workspace, repo_slug, pr_id, and task_id are required")
+	}
+
+	return GetJSON[PRTask](c, fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/tasks/%d",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID, args.TaskID))
+}
+
+type CreatePRTaskArgs struct {
+	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
+	PRID      int    `json:"pr_id" jsonschema:"Pull request ID"`
+	Content   string `json:"content" jsonschema:"Task description"`
+	CommentID int    `json:"comment_id,omitempty" jsonschema:"Comment ID to anchor the task to"`
+}
+
+// CreatePRTask creates a task on a pull request, anchored to a comment when
+// CommentID is set.
+func (c *Client) CreatePRTask(args CreatePRTaskArgs) (*PRTask, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 || args.Content == "" {
+		return nil, fmt.Errorf("workspace, repo_slug, pr_id, and content are required")
+	}
+
+	body := map[string]interface{}{
+		"content": map[string]string{"raw": args.Content},
+	}
+	if args.CommentID > 0 {
+		body["comment"] = ParentRef{ID: args.CommentID}
+	}
+
+	respData, err := c.Post(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/tasks",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task: %v", err)
+	}
+
+	var task PRTask
+	if err := json.Unmarshal(respData, &task); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	return &task, nil
+}
+
+type UpdatePRTaskArgs struct {
+	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
+	PRID      int    `json:"pr_id" jsonschema:"Pull request ID"`
+	TaskID    int    `json:"task_id" jsonschema:"Task ID"`
+	Content   string `json:"content,omitempty" jsonschema:"New task description"`
+	State     string `json:"state,omitempty" jsonschema:"New state (RESOLVED, UNRESOLVED)"`
+}
+
+// UpdatePRTask changes a task's description or state.
+func (c *Client) UpdatePRTask(args UpdatePRTaskArgs) (*PRTask, error) {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 || args.TaskID == 0 {
+		return nil, fmt.Errorf("workspace, repo_slug, pr_id, and task_id are required")
+	}
+	if args.Content == "" && args.State == "" {
+		return nil, fmt.Errorf("content or state is required")
+	}
+
+	body := map[string]interface{}{}
+	if args.Content != "" {
+		body["content"] = map[string]string{"raw": args.Content}
+	}
+	if args.State != "" {
+		body["state"] = args.State
+	}
+
+	respData, err := c.Put(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/tasks/%d",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID, args.TaskID), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update task: %v", err)
+	}
+
+	var task PRTask
+	if err := json.Unmarshal(respData, &task); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	return &task, nil
+}
+
+// DeletePRTask deletes a pull request task.
+func (c *Client) DeletePRTask(args PRTaskArgs) error {
+	if args.Workspace == "" || args.RepoSlug == "" || args.PRID == 0 || args.TaskID == 0 {
+		return fmt.Errorf("workspace, repo_slug, pr_id, and task_id are required")
+	}
+
+	return c.Delete(fmt.Sprintf("/repositories/%s/%s/pullrequests/%d/tasks/%d",
+		QueryEscape(args.Workspace), QueryEscape(args.RepoSlug), args.PRID, args.TaskID))
+}
@@ -37,6 +37,10 @@ type Branch struct {
 	Target *Commit `json:"target"`
 	Type   string  `json:"type"`
 	Links  Links   `json:"links"`
+
+	// Set on pull request destinations: the strategies the branch accepts.
+	MergeStrategies      []string `json:"merge_strategies,omitempty"`
+	DefaultMergeStrategy string   `json:"default_merge_strategy,omitempty"`
 }
 
 // Tag represents a tag ref.
@@ -151,9 +155,11 @@ type Content struct {
 
 // Inline represents inline comment location.
 type Inline struct {
-	From *int   `json:"from"`
-	To   *int   `json:"to"`
-	Path string `json:"path"`
+	From      *int   `json:"from"`
+	To        *int   `json:"to"`
+	StartFrom *int   `json:"start_from,omitempty"`
+	StartTo   *int   `json:"start_to,omitempty"`
+	Path      string `json:"path"`
 }
 
 // ParentRef points to a parent comment.
@@ -161,6 +167,71 @@ type ParentRef struct {
 	ID int `json:"id"`
 }
 
+// PRTask represents a task on a pull request, optionally anchored to a comment.
+type PRTask struct {
+	ID         int        `json:"id"`
+	Content    Content    `json:"content"`
+	State      string     `json:"state"`
+	Creator    *User      `json:"creator"`
+	ResolvedBy *User      `json:"resolved_by"`
+	Comment    *ParentRef `json:"comment"`
+	Pending    bool       `json:"pending"`
+	CreatedOn  time.Time  `json:"created_on"`
+	UpdatedOn  time.Time  `json:"updated_on"`
+	ResolvedOn *time.Time `json:"resolved_on"`
+	Links      Links      `json:"links"`
+}
+
+// PRActivity is one entry of a pull request's activity log. Exactly one of
+// its fields is set.
+type PRActivity struct {
+	Update           *PRUpdate   `json:"update,omitempty"`
+	Approval         *PRApproval `json:"approval,omitempty"`
+	ChangesRequested *PRApproval `json:"changes_requested,omitempty"`
+	Comment          *PRComment  `json:"comment,omitempty"`
+}
+
+// PRUpdate is a snapshot of a pull request taken when it was opened or changed.
+type PRUpdate struct {
+	State       string     `json:"state"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Reason      string     `json:"reason"`
+	Author      *User      `json:"author"`
+	Date        time.Time  `json:"date"`
+	Source      PREndpoint `json:"source"`
+	Destination PREndpoint `json:"destination"`
+	Reviewers   []User     `json:"reviewers"`
+}
+
+// PRApproval records a reviewer approving or requesting changes.
+type PRApproval struct {
+	User *User     `json:"user"`
+	Date time.Time `json:"date"`
+}
+
+// CommitStatus is a build or other check reported against a commit.
+type CommitStatus struct {
+	Key         string    `json:"key"`
+	Name        string    `json:"name"`
+	State       string    `json:"state"` // SUCCESSFUL, FAILED, INPROGRESS or STOPPED
+	Description string    `json:"description"`
+	URL         string    `json:"url"`
+	Refname     string    `json:"refname,omitempty"`
+	CreatedOn   time.Time `json:"created_on"`
+	UpdatedOn   time.Time `json:"updated_on"`
+}
+
+// BranchRestriction is a branch permission or merge check on a repository.
+type BranchRestriction struct {
+	ID              int    `json:"id"`
+	Kind            string `json:"kind"`
+	BranchMatchKind string `json:"branch_match_kind"`
+	BranchType      string `json:"branch_type,omitempty"`
+	Pattern         string `json:"pattern"`
+	Value           *int   `json:"value,omitempty"`
+}
+
 // Pipeline represents a pipeline run.
 type Pipeline struct {
 	UUID         string      `json:"uuid"`
@@ -253,13 +324,36 @@ type APIError struct {
 
 // CreatePRRequest is the body for creating a pull request.
 type CreatePRRequest struct {
-	Title             string     `json:"title"`
-	Description       string     `json:"description,omitempty"`
-	Source            PREndpoint `json:"source"`
-	Destination       PREndpoint `json:"destination,omitempty"`
-	CloseSourceBranch bool       `json:"close_source_branch,omitempty"`
-	Reviewers         []User     `json:"reviewers,omitempty"`
-	Draft             bool       `json:"draft,omitempty"`
+	Title             string        `json:"title"`
+	Description       string        `json:"description,omitempty"`
+	Source            PREndpoint    `json:"source"`
+	Destination       PREndpoint    `json:"destination,omitempty"`
+	CloseSourceBranch bool          `json:"close_source_branch,omitempty"`
+	Reviewers         []ReviewerRef `json:"reviewers,omitempty"`
+	Draft             bool          `json:"draft,omitempty"`
+}
+
+// ReviewerRef identifies a pull request reviewer by UUID or Atlassian account ID.
+type ReviewerRef struct {
+	UUID      string `json:"uuid,omitempty"`
+	AccountID string `json:"account_id,omitempty"`
+}
+
+// Matches reports whether u is the user r refers to.
+func (r ReviewerRef) Matches(u User) bool {
+	return (r.UUID != "" && r.UUID == u.UUID) || (r.AccountID != "" && r.AccountID == u.AccountID)
+}
+
+// DefaultReviewer is a user added to new pull requests in a repository.
+type DefaultReviewer struct {
+	User         *User  `json:"user"`
+	ReviewerType string `json:"reviewer_type"`
+}
+
+// WorkspaceMembership links a user to a workspace.
+type WorkspaceMembership struct {
+	User      *User      `json:"user"`
+	Workspace *Workspace `json:"workspace"`
 }
 
 // CreateBranchRequest is the body for creating a branch.
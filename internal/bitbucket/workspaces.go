@@ -3,11 +3,14 @@ package bitbucket
 import (
 	"fmt"
 	"net/url"
+	"regexp"
+	"strings"
 )
 
 type ListWorkspacesArgs struct {
-	Pagelen int `json:"pagelen,omitempty" jsonschema:"Number of results per page (default 25, max 100)"`
-	Page    int `json:"page,omitempty" jsonschema:"Page number (1-based)"`
+	Pagelen int  `json:"pagelen,omitempty" jsonschema:"Number of results per page (default 25, max 100)"`
+	Page    int  `json:"page,omitempty" jsonschema:"Page number (1-based)"`
+	All     bool `json:"all,omitempty" jsonschema:"Fetch every page instead of a single page"`
 }
 
 // ListWorkspaces returns workspaces for the authenticated user.
@@ -17,11 +20,17 @@ func (c *Client) ListWorkspaces(args ListWorkspacesArgs) (*Paginated[Workspace],
 		pagelen = 25
 	}
 	page := args.Page
-	if page == 0 {
+	if page == 0 || args.All {
 		page = 1
 	}
+	if args.All && args.Pagelen == 0 {
+		pagelen = 100
+	}
 
 	path := fmt.Sprintf("/workspaces?pagelen=%d&page=%d", pagelen, page)
+	if args.All {
+		return GetAllPaginated[Workspace](c, path, nil)
+	}
 	return GetPaginated[Workspace](c, path)
 }
 
@@ -37,3 +46,62 @@ func (c *Client) GetWorkspace(args GetWorkspaceArgs) (*Workspace, error) {
 
 	return GetJSON[Workspace](c, fmt.Sprintf("/workspaces/%s", url.QueryEscape(args.Workspace)))
 }
+
+// GetCurrentUser returns the user the client is authenticated as.
+func (c *Client) GetCurrentUser() (*User, error) {
+	return GetJSON[User](c, "/user")
+}
+
+// ListWorkspaceMembers returns every member of a workspace.
+func (c *Client) ListWorkspaceMembers(workspace string) (*Paginated[WorkspaceMembership], error) {
+	if workspace == "" {
+		return nil, fmt.Errorf("workspace is required")
+	}
+
+	return GetAllPaginated[WorkspaceMembership](c, fmt.Sprintf("/workspaces/%s/members?pagelen=100", url.QueryEscape(workspace)), nil)
+}
+
+// accountIDPattern matches Atlassian account IDs, both the "557058:<uuid>"
+// form and the older 24-digit hex form.
+var accountIDPattern = regexp.MustCompile(`^([0-9]+:[0-9a-fA-F-]{36}|[0-9a-fA-F]{24})$`)
+
+// ResolveReviewers turns account IDs, {UUID}s and nicknames into reviewer
+// references. Nicknames are looked up among the workspace members.
+func (c *Client) ResolveReviewers(workspace string, who []string) ([]ReviewerRef, error) {
+	var refs []ReviewerRef
+	var members []WorkspaceMembership
+	for _, w := range who {
+		w = strings.TrimSpace(w)
+		switch {
+		case w == "":
+			continue
+		case strings.HasPrefix(w, "{") && strings.HasSuffix(w, "}"):
+			refs = append(refs, ReviewerRef{UUID: w})
+			continue
+		case accountIDPattern.MatchString(w):
+			refs = append(refs, ReviewerRef{AccountID: w})
+			continue
+		}
+
+		if members == nil {
+			result, err := c.ListWorkspaceMembers(workspace)
+			if err != nil {
+				return nil, fmt.Errorf("failed to look up workspace members: %v", err)
+			}
+			members = result.Values
+		}
+		nick := strings.TrimPrefix(w, "@")
+		var found *User
+		for _, m := range members {
+			if m.User != nil && strings.EqualFold(m.User.Nickname, nick) {
+				found = m.User
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("no member of workspace '%s' has the nickname '%s'", workspace, nick)
+		}
+		refs = append(refs, ReviewerRef{UUID: found.UUID, AccountID: found.AccountID})
+	}
+	return refs, nil
+}
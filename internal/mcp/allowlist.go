@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// Allowlist restricts the workspaces and repositories the MCP server may act on.
+// Patterns are shell globs over "workspace/repo" (e.g. "acme/*", "*/infra-*").
+// A pattern without a slash matches every repository in that workspace.
+// A nil Allowlist permits everything.
+type Allowlist struct {
+	patterns []allowPattern
+}
+
+type allowPattern struct {
+	workspace string
+	repo      string
+}
+
+// ParseAllowlist builds an Allowlist from a list of glob patterns.
+// Empty entries are ignored; an empty list yields a nil (allow-all) Allowlist.
+func ParseAllowlist(patterns []string) (*Allowlist, error) {
+	var a Allowlist
+	for _, p := range patterns {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+
+		ws, repo, found := strings.Cut(p, "/")
+		if !found {
+			repo = "*"
+		}
+		if ws == "" || repo == "" || strings.Contains(repo, "/") {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: expected 'workspace/repo'", p)
+		}
+		// path.Match only reports malformed patterns when matching, so probe once here.
+		if _, err := path.Match(ws, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: %w", p, err)
+		}
+		if _, err := path.Match(repo, ""); err != nil {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: %w", p, err)
+		}
+
+		a.patterns = append(a.patterns, allowPattern{workspace: ws, repo: repo})
+	}
+
+	if len(a.patterns) == 0 {
+		return nil, nil
+	}
+	return &a, nil
+}
+
+// Allows reports whether the repository workspace/repoSlug matches the allowlist.
+func (a *Allowlist) Allows(workspace, repoSlug string) bool {
+	if a == nil {
+		return true
+	}
+	workspace, repoSlug = strings.ToLower(workspace), strings.ToLower(repoSlug)
+	for _, p := range a.patterns {
+		if globMatch(p.workspace, workspace) && globMatch(p.repo, repoSlug) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsWorkspace reports whether at least one repository in the workspace could match.
+func (a *Allowlist) AllowsWorkspace(workspace string) bool {
+	if a == nil {
+		return true
+	}
+	workspace = strings.ToLower(workspace)
+	for _, p := range a.patterns {
+		if globMatch(p.workspace, workspace) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the normalized patterns, comma separated.
+func (a *Allowlist) String() string {
+	if a == nil {
+		return "*/*"
+	}
+	parts := make([]string, len(a.patterns))
+	for i, p := range a.patterns {
+		parts[i] = p.workspace + "/" + p.repo
+	}
+	return strings.Join(parts, ",")
+}
+
+func globMatch(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// filterPage keeps the listed items keep allows. Bitbucket's size and next
+// would still count the hidden ones, so callers fetch every page and the
+// pagination fields are recomputed from what is left.
+func filterPage[T any](p *bitbucket.Paginated[T], keep func(T) bool) {
+	visible := p.Values[:0]
+	for _, v := range p.Values {
+		if keep(v) {
+			visible = append(visible, v)
+		}
+	}
+	p.Values = visible
+	p.Size, p.PageLen = len(visible), len(visible)
+	p.Next, p.Previous = "", ""
+}
@@ -0,0 +1,173 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/audit"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// mutatingActions lists, per tool, the actions that change state in Bitbucket
+// and therefore must be written to the audit log.
+var mutatingActions = map[string]map[string]bool{
+	"manage_repositories":  {"create": true, "delete": true},
+	"manage_refs":          {"create-branch": true, "delete-branch": true, "create-tag": true},
+	"manage_pull_requests": {"create": true, "update": true, "merge": true, "merge-when-ready": true, "approve": true, "unapprove": true, "request-changes": true, "remove-request-changes": true, "decline": true},
+	"manage_pr_comments":   {"create": true, "update": true, "delete": true, "resolve": true, "unresolve": true},
+	"manage_pr_tasks":      {"create": true, "update": true, "resolve": true, "reopen": true, "delete": true},
+	"manage_source":        {"write_file": true, "delete_file": true},
+	"manage_pipelines":     {"trigger": true, "stop": true},
+	"manage_issues":        {"create": true, "update": true},
+}
+
+// isMutating reports whether toolName/action changes state in Bitbucket.
+func isMutating(toolName, action string) bool {
+	return mutatingActions[toolName][action]
+}
+
+// recordCall writes an audit entry for a mutating tool call; calls whose output
+// shows they ran as a dry run are skipped. Failures to write are reported on
+// stderr rather than failing the call that already happened.
+func (r *toolRegistry) recordCall(toolName string, t toolTarget, req *mcp.CallToolRequest, res *mcp.CallToolResult, out any, outcome string) {
+	if r.opts.AuditLog == nil || !isMutating(toolName, t.Action) || dryRun(out) {
+		return
+	}
+
+	r.mu.Lock()
+	profile, user := r.profile, r.user
+	r.mu.Unlock()
+	if t.Profile != "" && r.opts.Profiles != nil {
+		profile, user = t.Profile, ""
+		if pc, ok := r.opts.Profiles.loaded(t.Profile); ok {
+			user = pc.user
+		}
+	}
+
+	entry := &audit.Entry{
+		Profile:   profile,
+		User:      user,
+		Tool:      toolName,
+		Action:    t.Action,
+		Workspace: t.Workspace,
+		RepoSlug:  t.RepoSlug,
+		Outcome:   outcome,
+	}
+	if req != nil && req.Params != nil {
+		entry.Arguments = redactArguments(toolName, req.Params.Arguments)
+	}
+
+	if res != nil {
+		if res.IsError {
+			if entry.Outcome == audit.OutcomeSuccess {
+				entry.Outcome = audit.OutcomeError
+			}
+			entry.Error = resultText(res)
+		} else {
+			entry.Objects = extractObjectIDs(out)
+		}
+	}
+
+	if err := r.opts.AuditLog.Write(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to write audit log entry: %v\n", err)
+	}
+}
+
+// dryRun reports whether a handler's output shows the call changed nothing: a
+// drafted pull request that was not created, or merge checks run without
+// merging. The preview argument alone proves nothing, since most actions ignore it.
+func dryRun(out any) bool {
+	switch v := out.(type) {
+	case *prDraft:
+		return true
+	case *bitbucket.MergeResult:
+		return v.CheckOnly
+	}
+	return false
+}
+
+// redactArguments decodes raw tool arguments, replacing file contents and
+// anything that looks like a secret with a hash.
+func redactArguments(toolName string, raw json.RawMessage) map[string]any {
+	if len(raw) == 0 {
+		return nil
+	}
+	var args map[string]any
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return map[string]any{"_raw": audit.Hash(string(raw))}
+	}
+
+	for k, v := range args {
+		s, ok := v.(string)
+		if !ok || s == "" {
+			continue
+		}
+		if isSensitiveArgument(toolName, k) {
+			args[k] = audit.Hash(s)
+		}
+	}
+	return args
+}
+
+func isSensitiveArgument(toolName, key string) bool {
+	if toolName == "manage_source" && key == "content" {
+		return true
+	}
+	k := strings.ToLower(key)
+	return strings.Contains(k, "token") || strings.Contains(k, "password") || strings.Contains(k, "secret")
+}
+
+// extractObjectIDs pulls identifiers of the created or modified object out of
+// the Bitbucket object a handler returned.
+func extractObjectIDs(out any) map[string]string {
+	if out == nil {
+		return nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return nil
+	}
+	var obj map[string]any
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return nil
+	}
+	// Merges wrap the merged pull request in their result.
+	if pr, ok := obj["pullrequest"].(map[string]any); ok {
+		obj = pr
+	}
+
+	ids := make(map[string]string)
+	for _, key := range []string{"id", "uuid", "hash", "name", "build_number"} {
+		switch v := obj[key].(type) {
+		case string:
+			if v != "" {
+				ids[key] = v
+			}
+		case float64:
+			ids[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	}
+	if mc, ok := obj["merge_commit"].(map[string]any); ok {
+		if h, ok := mc["hash"].(string); ok && h != "" {
+			ids["merge_commit"] = h
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return ids
+}
+
+func resultText(res *mcp.CallToolResult) string {
+	var parts []string
+	for _, c := range res.Content {
+		if tc, ok := c.(*mcp.TextContent); ok {
+			parts = append(parts, tc.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -2,7 +2,6 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -19,6 +18,7 @@ type ManageRefsArgs struct {
 	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page (for list)"`
 	Query     string `json:"query,omitempty" jsonschema:"Filter query (for list-branches)"`
 	Sort      string `json:"sort,omitempty" jsonschema:"Sort field (for list-branches)"`
+	Format    string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
 // ManageRefsHandler handles the consolidated branch and tag operations.
@@ -37,8 +37,7 @@ func ManageRefsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolR
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list branches: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return branchesMarkdown(result) })
 
 		case "create-branch":
 			if args.Name == "" || args.Target == "" {
@@ -53,8 +52,7 @@ func ManageRefsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolR
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to create branch: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(branch, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, branch, func() string { return refMarkdown("Branch", branch.Name, branch.Target) })
 
 		case "delete-branch":
 			if args.Name == "" {
@@ -80,8 +78,7 @@ func ManageRefsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolR
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list tags: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return tagsMarkdown(result) })
 
 		case "create-tag":
 			if args.Name == "" || args.Target == "" {
@@ -96,8 +93,7 @@ func ManageRefsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolR
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to create tag: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(tag, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, tag, func() string { return refMarkdown("Tag", tag.Name, tag.Target) })
 
 		default:
 			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil
@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// DefaultResponseBudget is the default maximum size, in bytes, of a large
+// text payload returned by a single tool call (roughly 25k tokens).
+const DefaultResponseBudget = 100_000
+
+// BytesPerToken approximates how many bytes of code or diff make one model token.
+const BytesPerToken = 4
+
+// chunkBoundary picks where a truncated chunk of text may end.
+type chunkBoundary int
+
+const (
+	// boundaryLine ends chunks after a newline.
+	boundaryLine chunkBoundary = iota
+	// boundaryDiffFile ends chunks before a "diff --git" file header, falling
+	// back to a newline when a single file exceeds the budget.
+	boundaryDiffFile
+)
+
+// budgetCursor is the decoded form of the opaque continuation cursor. It binds
+// the cursor to the request it came from and to the content already returned,
+// so resuming against changed content is detected instead of silently skipping.
+type budgetCursor struct {
+	Key    string `json:"k"`
+	Offset int    `json:"o"`
+	Prefix string `json:"p"`
+}
+
+// budgetText returns the slice of text that fits the budget, starting at the
+// cursor position, followed by a continuation note with the next cursor when
+// more remains. key identifies the request (tool, action and target). A
+// budget of zero or less disables truncation.
+func budgetText(text, key, cursor string, budget int, boundary chunkBoundary) (string, error) {
+	data := []byte(text)
+	keyHash := shortHash([]byte(key))
+
+	start := 0
+	if cursor != "" {
+		c, err := decodeBudgetCursor(cursor)
+		if err != nil {
+			return "", err
+		}
+		if c.Key != keyHash {
+			return "", fmt.Errorf("cursor was issued for a different request")
+		}
+		if c.Offset > len(data) || shortHash(data[:c.Offset]) != c.Prefix {
+			return "", fmt.Errorf("content changed since the cursor was issued; request it again without a cursor")
+		}
+		start = c.Offset
+	}
+
+	rest := data[start:]
+	if budget <= 0 || len(rest) <= budget {
+		if start == 0 {
+			return text, nil
+		}
+		return fmt.Sprintf("%s\n\n[end of output: bytes %d-%d of %d]", rest, start, len(data), len(data)), nil
+	}
+
+	end := start + cutPoint(rest, budget, boundary)
+	next, _ := json.Marshal(budgetCursor{Key: keyHash, Offset: end, Prefix: shortHash(data[:end])})
+	return fmt.Sprintf("%s\n\n[truncated: showing bytes %d-%d of %d. Call again with cursor=%q to continue]",
+		data[start:end], start, end, len(data), base64.RawURLEncoding.EncodeToString(next)), nil
+}
+
+// cutPoint returns the length of the prefix of b (at most budget bytes) that
+// ends on the preferred boundary.
+func cutPoint(b []byte, budget int, boundary chunkBoundary) int {
+	window := b[:budget]
+
+	if boundary == boundaryDiffFile {
+		// Never cut at offset 0: a chunk must make progress.
+		if i := bytes.LastIndex(window, []byte("\ndiff --git ")); i > 0 {
+			return i + 1
+		}
+	}
+	if i := bytes.LastIndexByte(window, '\n'); i >= 0 {
+		return i + 1
+	}
+
+	// A single line longer than the budget: cut without splitting a rune.
+	n := budget
+	for n > 0 && !utf8.RuneStart(b[n]) {
+		n--
+	}
+	if n == 0 {
+		n = budget
+	}
+	return n
+}
+
+func decodeBudgetCursor(s string) (*budgetCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c budgetCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Offset < 0 {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &c, nil
+}
+
+func shortHash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
@@ -2,7 +2,6 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -18,10 +17,15 @@ type ManagePRCommentsArgs struct {
 	Content   string `json:"content,omitempty" jsonschema:"Markdown content (for 'create', 'update')"`
 	ParentID  int    `json:"parent_id,omitempty" jsonschema:"Parent comment ID to reply to (for 'create')"`
 	FilePath  string `json:"file_path,omitempty" jsonschema:"File path for inline comments (for 'create')"`
-	LineFrom  int    `json:"line_from,omitempty" jsonschema:"Line number the comment applies to for deleted lines (for 'create')"`
-	LineTo    int    `json:"line_to,omitempty" jsonschema:"Line number the comment applies to for new/modified lines (for 'create')"`
+	LineFrom  int    `json:"line_from,omitempty" jsonschema:"Line number the comment applies to for deleted lines (for 'create'); same as line with side 'old'"`
+	LineTo    int    `json:"line_to,omitempty" jsonschema:"Line number the comment applies to for new/modified lines (for 'create'); same as line with side 'new'"`
+	Line      int    `json:"line,omitempty" jsonschema:"Line of file_path to comment on, checked against the PR diff (for 'create')"`
+	EndLine   int    `json:"end_line,omitempty" jsonschema:"Last line of a multi-line inline comment starting at line (for 'create')"`
+	Side      string `json:"side,omitempty" jsonschema:"Diff side the line numbers refer to: 'new' (default) or 'old' for deleted lines (for 'create')" jsonschema_enum:"new,old"`
+	Snap      bool   `json:"snap,omitempty" jsonschema:"Move a line outside the diff to the nearest hunk instead of rejecting it (for 'create')"`
 	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
 	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page (default 50)"`
+	Format    string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
 // ManagePRCommentsHandler handles the consolidated PR comments operations.
@@ -39,28 +43,46 @@ func ManagePRCommentsHandler(c *bitbucket.Client) func(context.Context, *mcp.Cal
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list PR comments: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return commentsMarkdown(result) })
 
 		case "create":
 			if args.Content == "" {
 				return ToolResultError("content is required for 'create' action"), nil, nil
 			}
-			comment, err := c.CreatePRComment(bitbucket.CreatePRCommentArgs{
+			create := bitbucket.CreatePRCommentArgs{
 				Workspace: args.Workspace,
 				RepoSlug:  args.RepoSlug,
 				PRID:      args.PRID,
 				Content:   args.Content,
 				ParentID:  args.ParentID,
 				FilePath:  args.FilePath,
-				LineFrom:  args.LineFrom,
-				LineTo:    args.LineTo,
-			})
+			}
+			target, ok, err := inlineTarget(args)
+			if err != nil {
+				return ToolResultError(err.Error()), nil, nil
+			}
+			var note string
+			if ok {
+				anchor, err := c.AnchorPRComment(bitbucket.PullRequestActionArgs{
+					Workspace: args.Workspace,
+					RepoSlug:  args.RepoSlug,
+					PRID:      args.PRID,
+				}, target)
+				if err != nil {
+					return ToolResultError(fmt.Sprintf("invalid inline comment location: %v", err)), nil, nil
+				}
+				anchor.Apply(&create)
+				note = anchor.Note
+			}
+			comment, err := c.CreatePRComment(create)
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to create comment: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(comment, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			res, out, err := render(args.Format, comment, func() string { return commentMarkdown(comment) })
+			if note != "" && res != nil {
+				res.Content = append(res.Content, &mcp.TextContent{Text: "Inline location " + note})
+			}
+			return res, out, err
 
 		case "update":
 			if args.CommentID == 0 || args.Content == "" {
@@ -76,8 +98,7 @@ func ManagePRCommentsHandler(c *bitbucket.Client) func(context.Context, *mcp.Cal
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to update comment: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(comment, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, comment, func() string { return commentMarkdown(comment) })
 
 		case "delete":
 			if args.CommentID == 0 {
@@ -126,3 +147,25 @@ func ManagePRCommentsHandler(c *bitbucket.Client) func(context.Context, *mcp.Cal
 		}
 	}
 }
+
+// inlineTarget reads the requested inline location from args, accepting the
+// older line_to/line_from form. ok is false for general and file-level comments;
+// lines without a file_path are an error rather than a general comment.
+func inlineTarget(args ManagePRCommentsArgs) (t bitbucket.InlineTarget, ok bool, err error) {
+	t = bitbucket.InlineTarget{Path: args.FilePath, Side: args.Side, Line: args.Line, EndLine: args.EndLine, Snap: args.Snap}
+	switch {
+	case args.FilePath == "":
+		if args.Line > 0 || args.EndLine > 0 || args.LineTo > 0 || args.LineFrom > 0 {
+			return t, false, fmt.Errorf("file_path is required for inline comments")
+		}
+		return t, false, nil
+	case t.Line > 0:
+	case args.LineTo > 0:
+		t.Line, t.Side = args.LineTo, bitbucket.DiffSideNew
+	case args.LineFrom > 0:
+		t.Line, t.Side = args.LineFrom, bitbucket.DiffSideOld
+	default:
+		return t, false, nil
+	}
+	return t, true, nil
+}
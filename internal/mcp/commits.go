@@ -2,7 +2,6 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -10,21 +9,30 @@ import (
 )
 
 type ManageCommitsArgs struct {
-	Action    string `json:"action" jsonschema:"Action to perform: 'list', 'get', 'diff', 'diffstat'" jsonschema_enum:"list,get,diff,diffstat"`
-	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
-	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
-	Revision  string `json:"revision,omitempty" jsonschema:"Branch name or commit hash to list commits for"`
-	Commit    string `json:"commit,omitempty" jsonschema:"Commit hash (required for 'get')"`
-	Spec      string `json:"spec,omitempty" jsonschema:"Diff spec: single commit hash or 'hash1..hash2' (required for 'diff', 'diffstat')"`
-	Path      string `json:"path,omitempty" jsonschema:"Filter diff/commits to this file path"`
-	Include   string `json:"include,omitempty" jsonschema:"Include commits reachable from this ref (for 'list')"`
-	Exclude   string `json:"exclude,omitempty" jsonschema:"Exclude commits reachable from this ref (for 'list')"`
-	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
-	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page"`
+	Action           string   `json:"action" jsonschema:"Action to perform: 'list', 'get', 'diff', 'diffstat', 'statuses'" jsonschema_enum:"list,get,diff,diffstat,statuses"`
+	Workspace        string   `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug         string   `json:"repo_slug" jsonschema:"Repository slug"`
+	Revision         string   `json:"revision,omitempty" jsonschema:"Branch name or commit hash to list commits for"`
+	Commit           string   `json:"commit,omitempty" jsonschema:"Commit hash (required for 'get', 'statuses')"`
+	Key              string   `json:"key,omitempty" jsonschema:"Build status key, to get a single status (for 'statuses')"`
+	Spec             string   `json:"spec,omitempty" jsonschema:"Diff spec: single commit hash or 'hash1..hash2' (required for 'diff', 'diffstat')"`
+	Path             string   `json:"path,omitempty" jsonschema:"Filter diff/commits to this file path"`
+	Context          *int     `json:"context,omitempty" jsonschema:"Lines of context around each change (default 3) (for 'diff')"`
+	IgnoreWhitespace bool     `json:"ignore_whitespace,omitempty" jsonschema:"Ignore whitespace-only changes (for 'diff')"`
+	ExcludeBinary    bool     `json:"exclude_binary,omitempty" jsonschema:"Leave binary files out of the diff (for 'diff')"`
+	Paths            []string `json:"paths,omitempty" jsonschema:"Only include files matching these paths, directories or globs such as 'src/api/*.go' (for 'diff')"`
+	PerFile          bool     `json:"per_file,omitempty" jsonschema:"Split the diff into per-file chunks with a diffstat; with format 'json' returns the chunks as an array (for 'diff')"`
+	Include          string   `json:"include,omitempty" jsonschema:"Include commits reachable from this ref (for 'list')"`
+	Exclude          string   `json:"exclude,omitempty" jsonschema:"Exclude commits reachable from this ref (for 'list')"`
+	Page             int      `json:"page,omitempty" jsonschema:"Page number"`
+	Pagelen          int      `json:"pagelen,omitempty" jsonschema:"Results per page"`
+	Cursor           string   `json:"cursor,omitempty" jsonschema:"Continuation cursor from a truncated 'diff' response"`
+	Format           string   `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
-// ManageCommitsHandler handles the consolidated commit operations.
-func ManageCommitsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolRequest, ManageCommitsArgs) (*mcp.CallToolResult, any, error) {
+// ManageCommitsHandler handles the consolidated commit operations. Diffs are
+// truncated to budget bytes per call (0 disables truncation).
+func ManageCommitsHandler(c *bitbucket.Client, budget int) func(context.Context, *mcp.CallToolRequest, ManageCommitsArgs) (*mcp.CallToolResult, any, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, args ManageCommitsArgs) (*mcp.CallToolResult, any, error) {
 		switch args.Action {
 		case "list":
@@ -41,8 +49,7 @@ func ManageCommitsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallTo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list commits: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return commitsMarkdown(result) })
 
 		case "get":
 			if args.Commit == "" {
@@ -56,23 +63,41 @@ func ManageCommitsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallTo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get commit: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(commit, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, commit, func() string { return commitMarkdown(commit) })
 
 		case "diff":
 			if args.Spec == "" {
 				return ToolResultError("spec is required for 'diff' action"), nil, nil
 			}
+			opts := bitbucket.DiffOptions{
+				Context:          args.Context,
+				IgnoreWhitespace: args.IgnoreWhitespace,
+				ExcludeBinary:    args.ExcludeBinary,
+				Paths:            args.Paths,
+			}
 			raw, err := c.GetDiff(bitbucket.GetDiffArgs{
 				Workspace: args.Workspace,
 				RepoSlug:  args.RepoSlug,
 				Spec:      args.Spec,
 				Path:      args.Path,
-			})
+			}, opts)
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get diff: %v", err)), nil, nil
 			}
-			return ToolResultText(string(raw)), nil, nil
+			if len(raw) == 0 && len(args.Paths) > 0 {
+				return ToolResultText("No changed files match the given paths."), nil, nil
+			}
+			text := string(raw)
+			if args.PerFile {
+				files := bitbucket.ParseDiff(raw)
+				text = renderText(args.Format, files, func() string { return fileDiffsMarkdown(files) })
+			}
+			key := fmt.Sprintf("commit-diff:%s/%s/%s:%s:%s:%t:%s", args.Workspace, args.RepoSlug, args.Spec, args.Path, opts, args.PerFile, args.Format)
+			text, err = budgetText(text, key, args.Cursor, budget, boundaryDiffFile)
+			if err != nil {
+				return ToolResultError(err.Error()), nil, nil
+			}
+			return ToolResultText(text), nil, nil
 
 		case "diffstat":
 			if args.Spec == "" {
@@ -86,8 +111,35 @@ func ManageCommitsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallTo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get diffstat: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return diffstatMarkdown(result) })
+
+		case "statuses":
+			if args.Commit == "" {
+				return ToolResultError("commit is required for 'statuses' action"), nil, nil
+			}
+			if args.Key != "" {
+				status, err := c.GetCommitStatus(bitbucket.CommitStatusArgs{
+					Workspace: args.Workspace,
+					RepoSlug:  args.RepoSlug,
+					Commit:    args.Commit,
+					Key:       args.Key,
+				})
+				if err != nil {
+					return ToolResultError(fmt.Sprintf("failed to get commit status: %v", err)), nil, nil
+				}
+				return render(args.Format, status, func() string { return statusMarkdown(status) })
+			}
+			result, err := c.ListCommitStatuses(bitbucket.ListCommitStatusesArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				Commit:    args.Commit,
+				Pagelen:   args.Pagelen,
+				Page:      args.Page,
+			})
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to list commit statuses: %v", err)), nil, nil
+			}
+			return render(args.Format, result, func() string { return statusesMarkdown(result) })
 
 		default:
 			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil
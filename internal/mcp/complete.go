@@ -0,0 +1,294 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// completionTTL is how long completion lookups are reused. It only needs to
+// cover a burst of keystrokes, so new branches and PRs show up quickly.
+const completionTTL = 30 * time.Second
+
+// maxCompletions is the most values a completion response may carry.
+const maxCompletions = 100
+
+// completionArgs maps argument names to the kind of value they hold.
+var completionArgs = map[string]string{
+	"workspace":          "workspace",
+	"repo_slug":          "repo",
+	"repo":               "repo",
+	"branch":             "branch",
+	"source_branch":      "branch",
+	"destination_branch": "branch",
+	"ref":                "ref",
+	"ref_name":           "ref",
+	"revision":           "ref",
+	"pr_id":              "pr",
+}
+
+// completionItem is a candidate value with an optional human-readable label.
+type completionItem struct {
+	Value string
+	Label string
+}
+
+type cachedCompletion struct {
+	items   []completionItem
+	expires time.Time
+}
+
+// completer answers completion/complete requests for workspace, repository,
+// branch, ref and pull request arguments, whatever prompt, resource or tool
+// they belong to.
+type completer struct {
+	client    *bitbucket.Client
+	allowlist *Allowlist
+	defaults  RepoDefaults
+
+	mu    sync.Mutex
+	cache map[string]cachedCompletion
+}
+
+func newCompleter(c *bitbucket.Client, opts *Options) *completer {
+	return &completer{
+		client:    c,
+		allowlist: opts.Allowlist,
+		defaults:  opts.Defaults,
+		cache:     make(map[string]cachedCompletion),
+	}
+}
+
+func (cp *completer) complete(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	empty := &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{Values: []string{}}}
+	if req == nil || req.Params == nil {
+		return empty, nil
+	}
+	arg := req.Params.Argument
+
+	var resolved map[string]string
+	if req.Params.Context != nil {
+		resolved = req.Params.Context.Arguments
+	}
+	workspace := resolved["workspace"]
+	if workspace == "" {
+		workspace = cp.defaults.Workspace
+	}
+	repoSlug := resolved["repo_slug"]
+	if repoSlug == "" && workspace == cp.defaults.Workspace {
+		repoSlug = cp.defaults.RepoSlug
+	}
+
+	kind := completionArgs[arg.Name]
+	switch kind {
+	case "":
+		return empty, nil
+	case "repo":
+		if workspace == "" {
+			return empty, nil
+		}
+	case "branch", "ref", "pr":
+		if workspace == "" || repoSlug == "" || !cp.allowlist.Allows(workspace, repoSlug) {
+			return empty, nil
+		}
+	}
+
+	var items []completionItem
+	var err error
+	switch kind {
+	case "workspace":
+		items, err = cp.workspaces()
+	case "repo":
+		items, err = cp.repositories(workspace, arg.Value)
+	case "branch":
+		items, err = cp.branches(workspace, repoSlug, arg.Value)
+	case "ref":
+		items, err = cp.refs(workspace, repoSlug, arg.Value)
+	case "pr":
+		items, err = cp.pullRequests(workspace, repoSlug)
+	}
+	if err != nil {
+		// Completion is a convenience; an API failure just means no suggestions.
+		return empty, nil
+	}
+
+	return completionResult(matchCompletions(items, arg.Value)), nil
+}
+
+// cached returns the items stored under key, calling fetch when they are
+// missing or stale.
+func (cp *completer) cached(key string, fetch func() ([]completionItem, error)) ([]completionItem, error) {
+	cp.mu.Lock()
+	entry, ok := cp.cache[key]
+	cp.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.items, nil
+	}
+
+	items, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	now := time.Now()
+	for k, e := range cp.cache {
+		if now.After(e.expires) {
+			delete(cp.cache, k)
+		}
+	}
+	cp.cache[key] = cachedCompletion{items: items, expires: now.Add(completionTTL)}
+	return items, nil
+}
+
+func (cp *completer) workspaces() ([]completionItem, error) {
+	return cp.cached("workspaces", func() ([]completionItem, error) {
+		result, err := cp.client.ListWorkspaces(bitbucket.ListWorkspacesArgs{Pagelen: 100})
+		if err != nil {
+			return nil, err
+		}
+		var items []completionItem
+		for _, w := range result.Values {
+			if cp.allowlist.AllowsWorkspace(w.Slug) {
+				items = append(items, completionItem{Value: w.Slug, Label: w.Name})
+			}
+		}
+		return items, nil
+	})
+}
+
+// repositories narrows the lookup on the server by the typed prefix, since a
+// workspace may hold far more repositories than one page.
+func (cp *completer) repositories(workspace, prefix string) ([]completionItem, error) {
+	if !cp.allowlist.AllowsWorkspace(workspace) {
+		return nil, nil
+	}
+	return cp.cached("repos:"+workspace+":"+prefix, func() ([]completionItem, error) {
+		args := bitbucket.ListRepositoriesArgs{Workspace: workspace, Pagelen: 100, Sort: "-updated_on"}
+		if prefix != "" {
+			args.Query = fmt.Sprintf(`name ~ "%s"`, bbqlEscape(prefix))
+		}
+		result, err := cp.client.ListRepositories(args)
+		if err != nil {
+			return nil, err
+		}
+		var items []completionItem
+		for _, r := range result.Values {
+			if cp.allowlist.Allows(workspace, r.Slug) {
+				items = append(items, completionItem{Value: r.Slug, Label: r.Name})
+			}
+		}
+		return items, nil
+	})
+}
+
+func (cp *completer) branches(workspace, repoSlug, prefix string) ([]completionItem, error) {
+	return cp.cached("branches:"+workspace+"/"+repoSlug+":"+prefix, func() ([]completionItem, error) {
+		args := bitbucket.ListBranchesArgs{Workspace: workspace, RepoSlug: repoSlug, Pagelen: 100, Sort: "-target.date"}
+		if prefix != "" {
+			args.Query = fmt.Sprintf(`name ~ "%s"`, bbqlEscape(prefix))
+		}
+		result, err := cp.client.ListBranches(args)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]completionItem, 0, len(result.Values))
+		for _, b := range result.Values {
+			items = append(items, completionItem{Value: b.Name})
+		}
+		return items, nil
+	})
+}
+
+// refs suggests branches followed by tags.
+func (cp *completer) refs(workspace, repoSlug, prefix string) ([]completionItem, error) {
+	branches, err := cp.branches(workspace, repoSlug, prefix)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := cp.cached("tags:"+workspace+"/"+repoSlug, func() ([]completionItem, error) {
+		result, err := cp.client.ListTags(bitbucket.ListTagsArgs{Workspace: workspace, RepoSlug: repoSlug, Pagelen: 100})
+		if err != nil {
+			return nil, err
+		}
+		items := make([]completionItem, 0, len(result.Values))
+		for _, t := range result.Values {
+			items = append(items, completionItem{Value: t.Name, Label: "tag"})
+		}
+		return items, nil
+	})
+	if err != nil {
+		return branches, nil
+	}
+	return append(slices.Clip(branches), tags...), nil
+}
+
+func (cp *completer) pullRequests(workspace, repoSlug string) ([]completionItem, error) {
+	return cp.cached("prs:"+workspace+"/"+repoSlug, func() ([]completionItem, error) {
+		result, err := cp.client.ListPullRequests(bitbucket.ListPullRequestsArgs{
+			Workspace: workspace,
+			RepoSlug:  repoSlug,
+			State:     "OPEN",
+			Pagelen:   50,
+		})
+		if err != nil {
+			return nil, err
+		}
+		items := make([]completionItem, 0, len(result.Values))
+		for _, pr := range result.Values {
+			items = append(items, completionItem{Value: strconv.Itoa(pr.ID), Label: pr.Title})
+		}
+		return items, nil
+	})
+}
+
+// matchCompletions keeps the items whose value or label contains the typed
+// text, values starting with it first.
+func matchCompletions(items []completionItem, typed string) []completionItem {
+	q := strings.ToLower(typed)
+	var prefixed, contained []completionItem
+	for _, it := range items {
+		value := strings.ToLower(it.Value)
+		switch {
+		case strings.HasPrefix(value, q):
+			prefixed = append(prefixed, it)
+		case strings.Contains(value, q) || strings.Contains(strings.ToLower(it.Label), q):
+			contained = append(contained, it)
+		}
+	}
+	return append(prefixed, contained...)
+}
+
+// completionResult caps the matches to the protocol limit. Labels travel in
+// _meta (value to label) for clients that can show them, e.g. PR titles.
+func completionResult(items []completionItem) *mcp.CompleteResult {
+	res := &mcp.CompleteResult{Completion: mcp.CompletionResultDetails{
+		Values:  []string{},
+		Total:   len(items),
+		HasMore: len(items) > maxCompletions,
+	}}
+	labels := make(map[string]any)
+	for _, it := range items[:min(len(items), maxCompletions)] {
+		res.Completion.Values = append(res.Completion.Values, it.Value)
+		if it.Label != "" && it.Label != it.Value {
+			labels[it.Value] = it.Label
+		}
+	}
+	if len(labels) > 0 {
+		res.Meta = mcp.Meta{"labels": labels}
+	}
+	return res
+}
+
+// bbqlEscape escapes a value for a double-quoted Bitbucket query string.
+func bbqlEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
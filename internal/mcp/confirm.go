@@ -0,0 +1,137 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ConfirmFallback decides what happens to a destructive action when the
+// connected client cannot ask the human for confirmation via elicitation.
+type ConfirmFallback string
+
+const (
+	// ConfirmRefuse rejects destructive actions the human cannot confirm.
+	ConfirmRefuse ConfirmFallback = "refuse"
+	// ConfirmAllow executes destructive actions without confirmation.
+	ConfirmAllow ConfirmFallback = "allow"
+)
+
+// ParseConfirmFallback validates a fallback name, defaulting to ConfirmRefuse.
+func ParseConfirmFallback(s string) (ConfirmFallback, error) {
+	switch ConfirmFallback(strings.ToLower(strings.TrimSpace(s))) {
+	case "", ConfirmRefuse:
+		return ConfirmRefuse, nil
+	case ConfirmAllow:
+		return ConfirmAllow, nil
+	}
+	return "", fmt.Errorf("invalid confirmation fallback %q: expected 'refuse' or 'allow'", s)
+}
+
+// confirmation describes what the human must type to approve a destructive action.
+type confirmation struct {
+	message string
+	expect  string
+}
+
+// destructiveActions maps "tool/action" to a builder for its confirmation prompt.
+var destructiveActions = map[string]func(t toolTarget) confirmation{
+	"manage_repositories/delete": func(t toolTarget) confirmation {
+		return confirmation{
+			message: fmt.Sprintf("Delete repository %s/%s? This cannot be undone. Type the repository slug to confirm.", t.Workspace, t.RepoSlug),
+			expect:  t.RepoSlug,
+		}
+	},
+	"manage_refs/delete-branch": func(t toolTarget) confirmation {
+		return confirmation{
+			message: fmt.Sprintf("Delete branch '%s' in %s/%s? Type the branch name to confirm.", t.Name, t.Workspace, t.RepoSlug),
+			expect:  t.Name,
+		}
+	},
+	"manage_pull_requests/merge": func(t toolTarget) confirmation {
+		return confirmation{
+			message: fmt.Sprintf("Merge pull request #%d in %s/%s? Type the PR number to confirm.", t.PRID, t.Workspace, t.RepoSlug),
+			expect:  strconv.Itoa(t.PRID),
+		}
+	},
+	"manage_pull_requests/merge-when-ready": func(t toolTarget) confirmation {
+		return confirmation{
+			message: fmt.Sprintf("Merge pull request #%d in %s/%s once its checks pass? Type the PR number to confirm.", t.PRID, t.Workspace, t.RepoSlug),
+			expect:  strconv.Itoa(t.PRID),
+		}
+	},
+	"manage_pull_requests/decline": func(t toolTarget) confirmation {
+		return confirmation{
+			message: fmt.Sprintf("Decline pull request #%d in %s/%s? Type the PR number to confirm.", t.PRID, t.Workspace, t.RepoSlug),
+			expect:  strconv.Itoa(t.PRID),
+		}
+	},
+	"manage_source/delete_file": func(t toolTarget) confirmation {
+		return confirmation{
+			message: fmt.Sprintf("Delete file '%s' from %s/%s? Type the file path to confirm.", t.Path, t.Workspace, t.RepoSlug),
+			expect:  t.Path,
+		}
+	},
+}
+
+// dryRunActions are the destructive actions that, called with preview set,
+// only run their checks and change nothing.
+var dryRunActions = map[string]bool{
+	"manage_pull_requests/merge": true,
+}
+
+// confirmDestructive asks the human to approve destructive actions through MCP
+// elicitation. It returns a refusal message, or "" when the call may proceed.
+func confirmDestructive(ctx context.Context, req *mcp.CallToolRequest, fallback ConfirmFallback, toolName string, t toolTarget) string {
+	key := toolName + "/" + t.Action
+	build, ok := destructiveActions[key]
+	if !ok || (t.Preview && dryRunActions[key]) {
+		return ""
+	}
+	c := build(t)
+
+	if !supportsElicitation(req) {
+		if fallback == ConfirmAllow {
+			return ""
+		}
+		return fmt.Sprintf("'%s' requires human confirmation, but this client does not support elicitation; refusing (start the server with --confirm-fallback=allow to override)", t.Action)
+	}
+
+	res, err := req.Session.Elicit(ctx, &mcp.ElicitParams{
+		Message: c.message,
+		RequestedSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"confirm": map[string]any{
+					"type":        "string",
+					"title":       "Confirmation",
+					"description": fmt.Sprintf("Type '%s' to confirm", c.expect),
+				},
+			},
+			"required": []string{"confirm"},
+		},
+	})
+	if err != nil {
+		return fmt.Sprintf("failed to obtain confirmation for '%s': %v", t.Action, err)
+	}
+	if res.Action != "accept" {
+		return fmt.Sprintf("'%s' was not confirmed by the user (%s)", t.Action, res.Action)
+	}
+
+	typed, _ := res.Content["confirm"].(string)
+	if strings.TrimSpace(typed) != c.expect {
+		return fmt.Sprintf("'%s' cancelled: confirmation text did not match '%s'", t.Action, c.expect)
+	}
+	return ""
+}
+
+func supportsElicitation(req *mcp.CallToolRequest) bool {
+	if req == nil || req.Session == nil {
+		return false
+	}
+	params := req.Session.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Elicitation != nil
+}
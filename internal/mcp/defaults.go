@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RepoDefaults is the workspace and repository assumed when a tool call omits them,
+// typically resolved from the directory the server was launched in.
+type RepoDefaults struct {
+	Workspace string
+	RepoSlug  string
+}
+
+// IsZero reports whether no defaults are configured.
+func (d RepoDefaults) IsZero() bool {
+	return d.Workspace == ""
+}
+
+// String returns "workspace/repo", or just the workspace when no repository is set.
+func (d RepoDefaults) String() string {
+	if d.RepoSlug == "" {
+		return d.Workspace
+	}
+	return d.Workspace + "/" + d.RepoSlug
+}
+
+// subjectField names, per tool, the argument that identifies the object being
+// acted on rather than its context, so a default must never stand in for it.
+var subjectField = map[string]string{
+	"manage_workspaces":   "workspace",
+	"manage_repositories": "repo_slug",
+}
+
+// defaultable reports whether field may be filled from the defaults for toolName.
+func (d RepoDefaults) defaultable(toolName, field string) bool {
+	if subjectField[toolName] == field || toolName == "manage_profiles" {
+		return false
+	}
+	switch field {
+	case "workspace":
+		return d.Workspace != ""
+	case "repo_slug":
+		return d.RepoSlug != "" && d.defaultable(toolName, "workspace")
+	}
+	return false
+}
+
+// inputSchema derives the input schema for In, with workspace and repo_slug
+// made optional and documented with their defaults when d provides them.
+func inputSchema[In any](toolName string, d RepoDefaults) (*jsonschema.Schema, error) {
+	schema, err := jsonschema.For[In](nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range map[string]string{"workspace": d.Workspace, "repo_slug": d.RepoSlug} {
+		prop, ok := schema.Properties[name]
+		if !ok || !d.defaultable(toolName, name) {
+			continue
+		}
+		prop.Description += fmt.Sprintf(" (defaults to '%s')", value)
+		schema.Required = slices.DeleteFunc(schema.Required, func(r string) bool { return r == name })
+	}
+	return schema, nil
+}
+
+// fillRepoDefaults adds the default workspace and repository to raw tool
+// arguments that omit them. The repository default only applies within the
+// default workspace. It reports whether anything was filled in.
+func fillRepoDefaults(toolName string, raw json.RawMessage, d RepoDefaults) (json.RawMessage, bool, error) {
+	args := make(map[string]any)
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &args); err != nil {
+			return raw, false, err
+		}
+	}
+
+	filled := false
+	if !d.defaultable(toolName, "workspace") {
+		return raw, false, nil
+	}
+	if ws, _ := args["workspace"].(string); ws == "" {
+		args["workspace"] = d.Workspace
+		filled = true
+	}
+	if d.defaultable(toolName, "repo_slug") && args["workspace"] == d.Workspace {
+		if repo, _ := args["repo_slug"].(string); repo == "" {
+			args["repo_slug"] = d.RepoSlug
+			filled = true
+		}
+	}
+	if !filled {
+		return raw, false, nil
+	}
+
+	patched, err := json.Marshal(args)
+	if err != nil {
+		return raw, false, err
+	}
+	return patched, true, nil
+}
+
+// echoTarget appends a note naming the repository a call acted on, so the
+// agent can tell when a server default was used.
+func echoTarget(res *mcp.CallToolResult, t toolTarget, defaulted bool) {
+	if res == nil || t.Workspace == "" {
+		return
+	}
+	target := t.Workspace
+	if t.RepoSlug != "" {
+		target += "/" + t.RepoSlug
+	}
+	note := "Repository: " + target
+	if defaulted {
+		note += " (server default)"
+	}
+	res.Content = append(res.Content, &mcp.TextContent{Text: note})
+}
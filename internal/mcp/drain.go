@@ -0,0 +1,65 @@
+package mcp
+
+import (
+	"context"
+	"sync"
+)
+
+// CallTracker counts in-flight tool calls so a server can drain them before
+// shutting down. The zero value is ready to use.
+type CallTracker struct {
+	mu       sync.Mutex
+	inFlight int
+	closing  bool
+	idle     chan struct{}
+}
+
+// start registers a new call. It returns false once Drain has been called.
+func (t *CallTracker) start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closing {
+		return false
+	}
+	t.inFlight++
+	return true
+}
+
+func (t *CallTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight--
+	if t.inFlight == 0 && t.idle != nil {
+		close(t.idle)
+		t.idle = nil
+	}
+}
+
+// InFlight returns the number of tool calls currently executing.
+func (t *CallTracker) InFlight() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.inFlight
+}
+
+// Drain refuses new tool calls and waits until the in-flight ones finish or ctx is done.
+func (t *CallTracker) Drain(ctx context.Context) error {
+	t.mu.Lock()
+	t.closing = true
+	if t.inFlight == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	if t.idle == nil {
+		t.idle = make(chan struct{})
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
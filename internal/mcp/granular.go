@@ -0,0 +1,289 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// ToolMode selects how tools are exposed to clients.
+type ToolMode string
+
+const (
+	// ToolModeUnified registers one manage_* tool per resource with an action argument.
+	ToolModeUnified ToolMode = "unified"
+	// ToolModeGranular registers one tool per action with precise required fields.
+	ToolModeGranular ToolMode = "granular"
+)
+
+// ParseToolMode validates a tool mode name, defaulting to ToolModeUnified.
+func ParseToolMode(s string) (ToolMode, error) {
+	switch ToolMode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", ToolModeUnified:
+		return ToolModeUnified, nil
+	case ToolModeGranular:
+		return ToolModeGranular, nil
+	}
+	return "", fmt.Errorf("invalid tool mode %q: expected 'unified' or 'granular'", s)
+}
+
+// toolAction describes one action of a unified tool exposed as its own tool
+// in granular mode.
+type toolAction struct {
+	Action      string
+	Name        string
+	Description string
+	// Fields are the arguments the action accepts; Required is the subset it needs.
+	Fields   []string
+	Required []string
+}
+
+var (
+	repoFields = []string{"workspace", "repo_slug"}
+	pageFields = []string{"page", "pagelen"}
+	// formatFields apply to actions that return Bitbucket objects.
+	formatFields = []string{"format"}
+	// diffFields shape the diff returned by the diff actions.
+	diffFields = []string{"context", "ignore_whitespace", "exclude_binary", "paths", "per_file"}
+)
+
+// fields concatenates argument name lists.
+func fields(groups ...[]string) []string {
+	var out []string
+	for _, g := range groups {
+		out = append(out, g...)
+	}
+	return out
+}
+
+// toolActions lists, per unified tool, the granular tool for each action.
+// Keep this in sync with the action switch of each handler.
+var toolActions = map[string][]toolAction{
+	"manage_workspaces": {
+		{Action: "list", Name: "workspace_list", Description: "List Bitbucket workspaces you have access to",
+			Fields: fields(pageFields, formatFields)},
+		{Action: "get", Name: "workspace_get", Description: "Get details for a Bitbucket workspace",
+			Fields: fields([]string{"workspace"}, formatFields), Required: []string{"workspace"}},
+	},
+	"manage_repositories": {
+		{Action: "list", Name: "repo_list", Description: "List repositories in a workspace",
+			Fields: fields([]string{"workspace", "query", "role", "sort", "all"}, pageFields, formatFields), Required: []string{"workspace"}},
+		{Action: "get", Name: "repo_get", Description: "Get details for a repository",
+			Fields: fields(repoFields, formatFields), Required: repoFields},
+		{Action: "create", Name: "repo_create", Description: "Create a new repository",
+			Fields: fields(repoFields, []string{"description", "language", "is_private", "project_key"}, formatFields), Required: repoFields},
+		{Action: "delete", Name: "repo_delete", Description: "Permanently delete a repository",
+			Fields: repoFields, Required: repoFields},
+	},
+	"manage_refs": {
+		{Action: "list-branches", Name: "branch_list", Description: "List branches in a repository",
+			Fields: fields(repoFields, []string{"query", "sort"}, pageFields, formatFields), Required: repoFields},
+		{Action: "create-branch", Name: "branch_create", Description: "Create a branch from a commit",
+			Fields: fields(repoFields, []string{"name", "target"}, formatFields), Required: fields(repoFields, []string{"name", "target"})},
+		{Action: "delete-branch", Name: "branch_delete", Description: "Delete a branch",
+			Fields: fields(repoFields, []string{"name"}), Required: fields(repoFields, []string{"name"})},
+		{Action: "list-tags", Name: "tag_list", Description: "List tags in a repository",
+			Fields: fields(repoFields, pageFields, formatFields), Required: repoFields},
+		{Action: "create-tag", Name: "tag_create", Description: "Create a tag on a commit",
+			Fields: fields(repoFields, []string{"name", "target"}, formatFields), Required: fields(repoFields, []string{"name", "target"})},
+	},
+	"manage_commits": {
+		{Action: "list", Name: "commit_list", Description: "List commits in a repository, optionally for a branch or path",
+			Fields: fields(repoFields, []string{"revision", "path", "include", "exclude"}, pageFields, formatFields), Required: repoFields},
+		{Action: "get", Name: "commit_get", Description: "Get details for a commit",
+			Fields: fields(repoFields, []string{"commit"}, formatFields), Required: fields(repoFields, []string{"commit"})},
+		{Action: "diff", Name: "commit_diff", Description: "Get the unified diff for a commit or 'hash1..hash2' range, optionally filtered to paths or split per file",
+			Fields: fields(repoFields, []string{"spec", "path", "cursor"}, diffFields, formatFields), Required: fields(repoFields, []string{"spec"})},
+		{Action: "diffstat", Name: "commit_diffstat", Description: "Get per-file change counts for a commit or range",
+			Fields: fields(repoFields, []string{"spec"}, formatFields), Required: fields(repoFields, []string{"spec"})},
+		{Action: "statuses", Name: "commit_statuses", Description: "List the build statuses reported for a commit, or get one by key",
+			Fields: fields(repoFields, []string{"commit", "key"}, pageFields, formatFields), Required: fields(repoFields, []string{"commit"})},
+	},
+	"manage_pull_requests": {
+		{Action: "list", Name: "pr_list", Description: "List pull requests in a repository",
+			Fields: fields(repoFields, []string{"state", "query", "all"}, pageFields, formatFields), Required: repoFields},
+		{Action: "get", Name: "pr_get", Description: "Get details for a pull request",
+			Fields: fields(repoFields, []string{"pr_id"}, formatFields), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "create", Name: "pr_create", Description: "Open a pull request from a source branch",
+			Fields:   fields(repoFields, []string{"title", "source_branch", "destination_branch", "description", "close_source_branch", "draft", "reviewers", "generate_description", "preview"}, formatFields),
+			Required: fields(repoFields, []string{"source_branch"})},
+		{Action: "update", Name: "pr_update", Description: "Update the title, description or reviewers of a pull request",
+			Fields:   fields(repoFields, []string{"pr_id", "title", "description", "add_reviewers", "remove_reviewers"}, formatFields),
+			Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "merge", Name: "pr_merge", Description: "Check approvals, builds and tasks, then merge a pull request (preview: checks only)",
+			Fields:   fields(repoFields, []string{"pr_id", "message", "merge_strategy", "close_source_branch", "preview"}, formatFields),
+			Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "merge-when-ready", Name: "pr_merge_when_ready", Description: "Wait for the checks enforced by branch restrictions to pass, then merge a pull request; aborts on new commits or a failed required build",
+			Fields:   fields(repoFields, []string{"pr_id", "message", "merge_strategy", "close_source_branch", "timeout", "poll_interval"}, formatFields),
+			Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "approve", Name: "pr_approve", Description: "Approve a pull request",
+			Fields: fields(repoFields, []string{"pr_id"}), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "unapprove", Name: "pr_unapprove", Description: "Remove your approval from a pull request",
+			Fields: fields(repoFields, []string{"pr_id"}), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "request-changes", Name: "pr_request_changes", Description: "Mark a pull request as needing changes",
+			Fields: fields(repoFields, []string{"pr_id"}), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "remove-request-changes", Name: "pr_remove_request_changes", Description: "Withdraw your change request from a pull request",
+			Fields: fields(repoFields, []string{"pr_id"}), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "decline", Name: "pr_decline", Description: "Decline a pull request",
+			Fields: fields(repoFields, []string{"pr_id"}), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "get-diff", Name: "pr_get_diff", Description: "Get the unified diff of a pull request, optionally filtered to paths or split per file",
+			Fields: fields(repoFields, []string{"pr_id", "cursor"}, diffFields, formatFields), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "get-diffstat", Name: "pr_get_diffstat", Description: "Get per-file change counts for a pull request",
+			Fields: fields(repoFields, []string{"pr_id"}, formatFields), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "get-commits", Name: "pr_get_commits", Description: "List the commits in a pull request",
+			Fields: fields(repoFields, []string{"pr_id"}, formatFields), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "statuses", Name: "pr_statuses", Description: "List the build statuses of a pull request's commits",
+			Fields: fields(repoFields, []string{"pr_id"}, formatFields), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "get-activity", Name: "pr_get_activity", Description: "Get the chronological history of a pull request: updates, reviews, comments and merges",
+			Fields: fields(repoFields, []string{"pr_id"}, formatFields), Required: fields(repoFields, []string{"pr_id"})},
+	},
+	"manage_pr_comments": {
+		{Action: "list", Name: "pr_comment_list", Description: "List comments on a pull request",
+			Fields: fields(repoFields, []string{"pr_id"}, pageFields, formatFields), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "create", Name: "pr_comment_create", Description: "Comment on a pull request, optionally as a reply or inline on lines of the diff",
+			Fields:   fields(repoFields, []string{"pr_id", "content", "parent_id", "file_path", "line", "end_line", "side", "snap", "line_from", "line_to"}, formatFields),
+			Required: fields(repoFields, []string{"pr_id", "content"})},
+		{Action: "update", Name: "pr_comment_update", Description: "Edit a pull request comment",
+			Fields: fields(repoFields, []string{"pr_id", "comment_id", "content"}, formatFields), Required: fields(repoFields, []string{"pr_id", "comment_id", "content"})},
+		{Action: "delete", Name: "pr_comment_delete", Description: "Delete a pull request comment",
+			Fields: fields(repoFields, []string{"pr_id", "comment_id"}), Required: fields(repoFields, []string{"pr_id", "comment_id"})},
+		{Action: "resolve", Name: "pr_comment_resolve", Description: "Resolve a pull request comment thread",
+			Fields: fields(repoFields, []string{"pr_id", "comment_id"}), Required: fields(repoFields, []string{"pr_id", "comment_id"})},
+		{Action: "unresolve", Name: "pr_comment_unresolve", Description: "Reopen a resolved pull request comment thread",
+			Fields: fields(repoFields, []string{"pr_id", "comment_id"}), Required: fields(repoFields, []string{"pr_id", "comment_id"})},
+	},
+	"manage_pr_tasks": {
+		{Action: "list", Name: "pr_task_list", Description: "List tasks on a pull request",
+			Fields: fields(repoFields, []string{"pr_id", "state"}, pageFields, formatFields), Required: fields(repoFields, []string{"pr_id"})},
+		{Action: "get", Name: "pr_task_get", Description: "Get a pull request task",
+			Fields: fields(repoFields, []string{"pr_id", "task_id"}, formatFields), Required: fields(repoFields, []string{"pr_id", "task_id"})},
+		{Action: "create", Name: "pr_task_create", Description: "Add a task to a pull request, optionally anchored to a comment",
+			Fields:   fields(repoFields, []string{"pr_id", "content", "comment_id"}, formatFields),
+			Required: fields(repoFields, []string{"pr_id", "content"})},
+		{Action: "update", Name: "pr_task_update", Description: "Edit a pull request task",
+			Fields: fields(repoFields, []string{"pr_id", "task_id", "content"}, formatFields), Required: fields(repoFields, []string{"pr_id", "task_id", "content"})},
+		{Action: "resolve", Name: "pr_task_resolve", Description: "Mark a pull request task as done",
+			Fields: fields(repoFields, []string{"pr_id", "task_id"}, formatFields), Required: fields(repoFields, []string{"pr_id", "task_id"})},
+		{Action: "reopen", Name: "pr_task_reopen", Description: "Reopen a resolved pull request task",
+			Fields: fields(repoFields, []string{"pr_id", "task_id"}, formatFields), Required: fields(repoFields, []string{"pr_id", "task_id"})},
+		{Action: "delete", Name: "pr_task_delete", Description: "Delete a pull request task",
+			Fields: fields(repoFields, []string{"pr_id", "task_id"}), Required: fields(repoFields, []string{"pr_id", "task_id"})},
+	},
+	"manage_source": {
+		{Action: "read_file", Name: "source_read_file", Description: "Read a file from a repository at a ref",
+			Fields: fields(repoFields, []string{"path", "ref", "cursor"}), Required: fields(repoFields, []string{"path"})},
+		{Action: "list_directory", Name: "source_list_directory", Description: "List files and directories at a path",
+			Fields: fields(repoFields, []string{"path", "ref", "max_depth", "pagelen"}, formatFields), Required: repoFields},
+		{Action: "get_history", Name: "source_get_history", Description: "List the commits that changed a file",
+			Fields: fields(repoFields, []string{"path", "ref", "pagelen"}, formatFields), Required: fields(repoFields, []string{"path"})},
+		{Action: "search", Name: "source_search", Description: "Search code in a repository",
+			Fields: fields(repoFields, []string{"query"}, pageFields, formatFields), Required: fields(repoFields, []string{"query"})},
+		{Action: "write_file", Name: "source_write_file", Description: "Commit new content for a file",
+			Fields:   fields(repoFields, []string{"path", "content", "message", "branch", "author"}),
+			Required: fields(repoFields, []string{"path", "content", "message"})},
+		{Action: "delete_file", Name: "source_delete_file", Description: "Commit the deletion of a file",
+			Fields:   fields(repoFields, []string{"path", "message", "branch", "author"}),
+			Required: fields(repoFields, []string{"path", "message"})},
+	},
+	"manage_pipelines": {
+		{Action: "list", Name: "pipeline_list", Description: "List pipeline runs in a repository",
+			Fields: fields(repoFields, []string{"sort", "status"}, pageFields, formatFields), Required: repoFields},
+		{Action: "get", Name: "pipeline_get", Description: "Get details for a pipeline run",
+			Fields: fields(repoFields, []string{"pipeline_uuid"}, formatFields), Required: fields(repoFields, []string{"pipeline_uuid"})},
+		{Action: "trigger", Name: "pipeline_trigger", Description: "Run a pipeline on a branch or tag",
+			Fields: fields(repoFields, []string{"ref_name", "ref_type", "pattern"}, formatFields), Required: fields(repoFields, []string{"ref_name"})},
+		{Action: "stop", Name: "pipeline_stop", Description: "Stop a running pipeline",
+			Fields: fields(repoFields, []string{"pipeline_uuid"}), Required: fields(repoFields, []string{"pipeline_uuid"})},
+		{Action: "list-steps", Name: "pipeline_list_steps", Description: "List the steps of a pipeline run",
+			Fields: fields(repoFields, []string{"pipeline_uuid"}, formatFields), Required: fields(repoFields, []string{"pipeline_uuid"})},
+		{Action: "get-step-log", Name: "pipeline_get_step_log", Description: "Get the log output of a pipeline step",
+			Fields:   fields(repoFields, []string{"pipeline_uuid", "step_uuid", "cursor"}),
+			Required: fields(repoFields, []string{"pipeline_uuid", "step_uuid"})},
+		{Action: "wait", Name: "pipeline_wait", Description: "Wait for a pipeline run to complete, reporting progress",
+			Fields:   fields(repoFields, []string{"pipeline_uuid", "timeout", "poll_interval"}, formatFields),
+			Required: fields(repoFields, []string{"pipeline_uuid"})},
+	},
+	"manage_issues": {
+		{Action: "list", Name: "issue_list", Description: "List issues in a repository's issue tracker",
+			Fields: fields(repoFields, []string{"state", "query"}, pageFields, formatFields), Required: repoFields},
+		{Action: "get", Name: "issue_get", Description: "Get details for an issue",
+			Fields: fields(repoFields, []string{"issue_id"}, formatFields), Required: fields(repoFields, []string{"issue_id"})},
+		{Action: "create", Name: "issue_create", Description: "Create an issue",
+			Fields:   fields(repoFields, []string{"title", "content", "state", "kind", "priority", "assignee"}, formatFields),
+			Required: fields(repoFields, []string{"title"})},
+		{Action: "update", Name: "issue_update", Description: "Update an issue",
+			Fields:   fields(repoFields, []string{"issue_id", "title", "content", "state", "kind", "priority", "assignee"}, formatFields),
+			Required: fields(repoFields, []string{"issue_id"})},
+	},
+	"manage_profiles": {
+		{Action: "list", Name: "profile_list", Description: "List the stored credential profiles tools can act as",
+			Fields: formatFields},
+		{Action: "get", Name: "profile_get", Description: "Get a stored credential profile and its token scopes",
+			Fields: fields([]string{"name"}, formatFields), Required: []string{"name"}},
+	},
+}
+
+// forAction strips "(for 'create', 'update')" style qualifiers that only make
+// sense in the unified schema.
+var forAction = regexp.MustCompile(`\s*\((?:required )?for '[^)]*\)`)
+
+// addActionTool registers a single action of a unified tool as its own tool.
+// The handler is shared with unified mode; the action is injected before the
+// call reaches the policy checks, which stay keyed by the unified tool name.
+func addActionTool[In any](r *toolRegistry, toolName string, a toolAction, newHandler func(*bitbucket.Client) toolHandler[In]) {
+	if r.disabled[a.Name] {
+		return
+	}
+
+	schema, err := inputSchema[In](toolName, r.opts.Defaults)
+	if err != nil {
+		panic(fmt.Sprintf("building schema for %s: %v", a.Name, err))
+	}
+	for name, prop := range schema.Properties {
+		if !slices.Contains(a.Fields, name) {
+			delete(schema.Properties, name)
+			continue
+		}
+		prop.Description = forAction.ReplaceAllString(prop.Description, "")
+	}
+	schema.Required = nil
+	for _, name := range a.Required {
+		if !r.opts.Defaults.defaultable(toolName, name) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	if r.profileArg(toolName) {
+		addProfileArg(schema)
+	}
+
+	tool := mcp.Tool{Name: a.Name, Description: a.Description, InputSchema: schema}
+	guarded := guardTool(r, toolName, newHandler)
+	actionJSON, _ := json.Marshal(map[string]string{"action": a.Action})
+
+	wrapped := func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		if err := json.Unmarshal(actionJSON, &args); err != nil {
+			return ToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil, nil
+		}
+		if req != nil && req.Params != nil {
+			raw := make(map[string]any)
+			if len(req.Params.Arguments) > 0 {
+				_ = json.Unmarshal(req.Params.Arguments, &raw)
+			}
+			raw["action"] = a.Action
+			req.Params.Arguments, _ = json.Marshal(raw)
+		}
+		return guarded(ctx, req, args)
+	}
+	r.tools = append(r.tools, registeredTool{
+		name:     a.Name,
+		toolName: toolName,
+		actions:  []string{a.Action},
+		add:      func([]string) { mcp.AddTool(r.server, &tool, wrapped) },
+	})
+}
@@ -2,7 +2,6 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -23,6 +22,7 @@ type ManageIssuesArgs struct {
 	Query     string `json:"query,omitempty" jsonschema:"Filter query (for 'list')"`
 	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
 	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page"`
+	Format    string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
 // ManageIssuesHandler handles the consolidated issue operations.
@@ -41,8 +41,7 @@ func ManageIssuesHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list issues: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return issuesMarkdown(result) })
 
 		case "get":
 			if args.IssueID == 0 {
@@ -56,8 +55,7 @@ func ManageIssuesHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get issue: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return issueMarkdown(result) })
 
 		case "create":
 			if args.Title == "" {
@@ -75,8 +73,7 @@ func ManageIssuesHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to create issue: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return issueMarkdown(result) })
 
 		case "update":
 			if args.IssueID == 0 {
@@ -122,8 +119,7 @@ func ManageIssuesHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to update issue: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return issueMarkdown(result) })
 
 		default:
 			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil
@@ -2,15 +2,15 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/zach-snell/bbkt/internal/bitbucket"
 )
 
 type ManagePipelinesArgs struct {
-	Action       string `json:"action" jsonschema:"Action to perform: 'list', 'get', 'trigger', 'stop', 'list-steps', 'get-step-log'" jsonschema_enum:"list,get,trigger,stop,list-steps,get-step-log"`
+	Action       string `json:"action" jsonschema:"Action to perform: 'list', 'get', 'trigger', 'stop', 'list-steps', 'get-step-log', 'wait'" jsonschema_enum:"list,get,trigger,stop,list-steps,get-step-log,wait"`
 	Workspace    string `json:"workspace" jsonschema:"Workspace slug"`
 	RepoSlug     string `json:"repo_slug" jsonschema:"Repository slug"`
 	PipelineUUID string `json:"pipeline_uuid,omitempty" jsonschema:"Pipeline UUID"`
@@ -22,10 +22,14 @@ type ManagePipelinesArgs struct {
 	Pagelen      int    `json:"pagelen,omitempty" jsonschema:"Results per page"`
 	Sort         string `json:"sort,omitempty" jsonschema:"Sort field"`
 	Status       string `json:"status,omitempty" jsonschema:"Filter by status"`
+	Timeout      int    `json:"timeout,omitempty" jsonschema:"Seconds to wait for the pipeline to complete (default 600, max 3600) (for 'wait')"`
+	PollInterval int    `json:"poll_interval,omitempty" jsonschema:"Seconds between status checks (default 10, min 5) (for 'wait')"`
+	Cursor       string `json:"cursor,omitempty" jsonschema:"Continuation cursor from a truncated 'get-step-log' response"`
+	Format       string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
 // ManagePipelinesHandler handles the consolidated pipeline operations.
-func ManagePipelinesHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolRequest, ManagePipelinesArgs) (*mcp.CallToolResult, any, error) {
+func ManagePipelinesHandler(c *bitbucket.Client, budget int) func(context.Context, *mcp.CallToolRequest, ManagePipelinesArgs) (*mcp.CallToolResult, any, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, args ManagePipelinesArgs) (*mcp.CallToolResult, any, error) {
 		switch args.Action {
 		case "list":
@@ -40,8 +44,7 @@ func ManagePipelinesHandler(c *bitbucket.Client) func(context.Context, *mcp.Call
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list pipelines: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return pipelinesMarkdown(result) })
 
 		case "get":
 			if args.PipelineUUID == "" {
@@ -55,8 +58,7 @@ func ManagePipelinesHandler(c *bitbucket.Client) func(context.Context, *mcp.Call
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get pipeline: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(pipe, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, pipe, func() string { return pipelineMarkdown(pipe) })
 
 		case "trigger":
 			if args.RefName == "" {
@@ -72,8 +74,7 @@ func ManagePipelinesHandler(c *bitbucket.Client) func(context.Context, *mcp.Call
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to trigger pipeline: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(pipe, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, pipe, func() string { return pipelineMarkdown(pipe) })
 
 		case "stop":
 			if args.PipelineUUID == "" {
@@ -100,8 +101,7 @@ func ManagePipelinesHandler(c *bitbucket.Client) func(context.Context, *mcp.Call
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list pipeline steps: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return stepsMarkdown(result) })
 
 		case "get-step-log":
 			if args.PipelineUUID == "" || args.StepUUID == "" {
@@ -116,10 +116,95 @@ func ManagePipelinesHandler(c *bitbucket.Client) func(context.Context, *mcp.Call
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get step log: %v", err)), nil, nil
 			}
-			return ToolResultText(string(raw)), nil, nil
+			key := fmt.Sprintf("step-log:%s/%s/%s/%s", args.Workspace, args.RepoSlug, args.PipelineUUID, args.StepUUID)
+			text, err := budgetText(string(raw), key, args.Cursor, budget, boundaryLine)
+			if err != nil {
+				return ToolResultError(err.Error()), nil, nil
+			}
+			return ToolResultText(text), nil, nil
+
+		case "wait":
+			if args.PipelineUUID == "" {
+				return ToolResultError("pipeline_uuid is required for 'wait' action"), nil, nil
+			}
+			pipe, err := waitForPipeline(ctx, c, args, newProgress(ctx, req))
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to wait for pipeline: %v", err)), nil, nil
+			}
+			return render(args.Format, pipe, func() string { return pipelineMarkdown(pipe) })
 
 		default:
 			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil
 		}
 	}
 }
+
+// waitForPipeline polls a pipeline until it completes, sending a progress
+// notification after every poll so clients do not time out the call.
+func waitForPipeline(ctx context.Context, c *bitbucket.Client, args ManagePipelinesArgs, progress *progressReporter) (*bitbucket.Pipeline, error) {
+	timeout := time.Duration(args.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Minute
+	}
+	timeout = min(timeout, time.Hour)
+	interval := max(time.Duration(args.PollInterval)*time.Second, 5*time.Second)
+	if args.PollInterval == 0 {
+		interval = 10 * time.Second
+	}
+
+	start := time.Now()
+	deadline := start.Add(timeout)
+	for {
+		pipe, err := c.GetPipeline(bitbucket.GetPipelineArgs{
+			Workspace:    args.Workspace,
+			RepoSlug:     args.RepoSlug,
+			PipelineUUID: args.PipelineUUID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if pipe.State != nil && pipe.State.Name == "COMPLETED" {
+			progress.report(timeout.Seconds(), timeout.Seconds(), fmt.Sprintf("pipeline #%d %s", pipe.BuildNumber, pipelineStatus(pipe)))
+			return pipe, nil
+		}
+
+		msg := fmt.Sprintf("pipeline #%d %s", pipe.BuildNumber, pipelineStatus(pipe))
+		if steps, err := c.ListPipelineSteps(bitbucket.ListPipelineStepsArgs{
+			Workspace:    args.Workspace,
+			RepoSlug:     args.RepoSlug,
+			PipelineUUID: args.PipelineUUID,
+		}); err == nil && len(steps.Values) > 0 {
+			done := 0
+			for _, step := range steps.Values {
+				if step.State != nil && step.State.Name == "COMPLETED" {
+					done++
+				}
+			}
+			msg += fmt.Sprintf(", %d/%d steps completed", done, len(steps.Values))
+		}
+		progress.report(time.Since(start).Seconds(), timeout.Seconds(), msg)
+
+		if time.Now().Add(interval).After(deadline) {
+			return nil, fmt.Errorf("timed out after %s; %s", timeout, msg)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// pipelineStatus describes a pipeline's state, preferring the final result.
+func pipelineStatus(p *bitbucket.Pipeline) string {
+	if p.State == nil {
+		return "UNKNOWN"
+	}
+	if p.State.Result != nil {
+		return p.State.Result.Name
+	}
+	if p.State.Stage != nil {
+		return p.State.Name + " (" + p.State.Stage.Name + ")"
+	}
+	return p.State.Name
+}
@@ -0,0 +1,265 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// ProfilePool serves tool calls that name a stored profile other than the one
+// the server started with. Clients are built from the profile store on first
+// use and cached, together with their token scopes, until Refresh.
+type ProfilePool struct {
+	mu         sync.Mutex
+	clients    map[string]*profileClient
+	registries []*toolRegistry
+}
+
+type profileClient struct {
+	client *bitbucket.Client
+	user   string
+	scopes []string
+}
+
+func (p *ProfilePool) add(r *toolRegistry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.registries = append(p.registries, r)
+}
+
+// get returns the client for the named profile, building it on first use.
+// A newly built client's scopes may widen the tool set, so the registries are
+// re-synced.
+func (p *ProfilePool) get(name string) (*profileClient, error) {
+	p.mu.Lock()
+	if pc, ok := p.clients[name]; ok {
+		p.mu.Unlock()
+		return pc, nil
+	}
+	p.mu.Unlock()
+
+	store, err := bitbucket.LoadProfileStore()
+	if err != nil {
+		return nil, err
+	}
+	creds, ok := store.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile '%s' not found; use manage_profiles to list profiles", name)
+	}
+	if !creds.IsAPIToken() && !creds.IsOAuth() {
+		return nil, fmt.Errorf("profile '%s' has unknown auth type '%s'", name, creds.AuthType)
+	}
+
+	pc := &profileClient{client: bitbucket.NewClientFromCredentials(creds), user: creds.Email}
+	if pc.scopes, err = pc.client.Scopes(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to fetch token scopes for profile %s: %v\n", name, err)
+	}
+
+	p.mu.Lock()
+	if existing, ok := p.clients[name]; ok {
+		p.mu.Unlock()
+		return existing, nil
+	}
+	if p.clients == nil {
+		p.clients = make(map[string]*profileClient)
+	}
+	p.clients[name] = pc
+	registries := append([]*toolRegistry(nil), p.registries...)
+	p.mu.Unlock()
+
+	for _, r := range registries {
+		r.resync()
+	}
+	return pc, nil
+}
+
+// loaded returns the cached client for name, if any.
+func (p *ProfilePool) loaded(name string) (*profileClient, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc, ok := p.clients[name]
+	return pc, ok
+}
+
+// scopeSets returns the scopes of every profile built so far.
+func (p *ProfilePool) scopeSets() [][]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	sets := make([][]string, 0, len(p.clients))
+	for _, pc := range p.clients {
+		sets = append(sets, pc.scopes)
+	}
+	return sets
+}
+
+// reset drops the cached clients so profiles are re-read from the store, e.g.
+// after the credentials file changed.
+func (p *ProfilePool) reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clients = nil
+}
+
+// unionScopes merges token scopes for exposing tools. An empty set means the
+// scopes are unknown, which allows everything, so it wins.
+func unionScopes(sets ...[]string) []string {
+	var union []string
+	for _, s := range sets {
+		if len(s) == 0 {
+			return nil
+		}
+		for _, scope := range s {
+			if !slices.Contains(union, scope) {
+				union = append(union, scope)
+			}
+		}
+	}
+	return union
+}
+
+// addProfileArg documents the profile argument on a tool's input schema.
+func addProfileArg(schema *jsonschema.Schema) {
+	if schema.Properties == nil {
+		schema.Properties = make(map[string]*jsonschema.Schema)
+	}
+	schema.Properties["profile"] = &jsonschema.Schema{
+		Type:        "string",
+		Description: "Stored credential profile to act as (default: the server's profile); see manage_profiles",
+	}
+}
+
+// ManageProfilesArgs holds the arguments for the manage_profiles tool.
+type ManageProfilesArgs struct {
+	Action string `json:"action" jsonschema:"Action to perform: 'list', 'get'" jsonschema_enum:"list,get"`
+	Name   string `json:"name,omitempty" jsonschema:"Profile name (for 'get')"`
+	Format string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
+}
+
+// profileInfo describes a stored profile without its secrets.
+type profileInfo struct {
+	Name       string    `json:"name"`
+	AuthType   string    `json:"auth_type"`
+	Email      string    `json:"email,omitempty"`
+	Default    bool      `json:"default"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"created_at"`
+	Workspaces []string  `json:"workspaces,omitempty"`
+	Loaded     bool      `json:"loaded"`
+	Scopes     []string  `json:"scopes,omitempty"`
+}
+
+func newProfileInfo(creds *bitbucket.Credentials, store *bitbucket.ProfileStore, current string) profileInfo {
+	return profileInfo{
+		Name:       creds.ProfileName,
+		AuthType:   string(creds.AuthType),
+		Email:      creds.Email,
+		Default:    creds.ProfileName == store.ActiveProfile,
+		Current:    creds.ProfileName == current,
+		CreatedAt:  creds.CreatedAt,
+		Workspaces: creds.AccessibleWorkspaces,
+	}
+}
+
+// ManageProfilesHandler lists the stored profiles tool calls can select. 'get'
+// builds the profile's client, so its scopes are known and reported.
+func ManageProfilesHandler(pool *ProfilePool, current func() string) func(context.Context, *mcp.CallToolRequest, ManageProfilesArgs) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, args ManageProfilesArgs) (*mcp.CallToolResult, any, error) {
+		store, err := bitbucket.LoadProfileStore()
+		if err != nil {
+			return ToolResultError(fmt.Sprintf("failed to load profiles: %v", err)), nil, nil
+		}
+
+		switch args.Action {
+		case "list":
+			profiles := make([]profileInfo, 0, len(store.Profiles))
+			for _, creds := range store.Profiles {
+				info := newProfileInfo(creds, store, current())
+				if pc, ok := pool.loaded(creds.ProfileName); ok {
+					info.Loaded, info.Scopes = true, pc.scopes
+				}
+				profiles = append(profiles, info)
+			}
+			sort.Slice(profiles, func(i, j int) bool { return profiles[i].Name < profiles[j].Name })
+			return render(args.Format, profiles, func() string { return profilesMarkdown(profiles) })
+
+		case "get":
+			if args.Name == "" {
+				return ToolResultError("name is required for 'get' action"), nil, nil
+			}
+			creds, ok := store.Profiles[args.Name]
+			if !ok {
+				return ToolResultError(fmt.Sprintf("profile '%s' not found", args.Name)), nil, nil
+			}
+			pc, err := pool.get(args.Name)
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to load profile: %v", err)), nil, nil
+			}
+			info := newProfileInfo(creds, store, current())
+			info.Loaded, info.Scopes = true, pc.scopes
+			return render(args.Format, info, func() string { return profileMarkdown(info) })
+
+		default:
+			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil
+		}
+	}
+}
+
+func profileFlags(p profileInfo) string {
+	var flags []string
+	if p.Default {
+		flags = append(flags, "default")
+	}
+	if p.Current {
+		flags = append(flags, "server")
+	}
+	return strings.Join(flags, ", ")
+}
+
+func profilesMarkdown(profiles []profileInfo) string {
+	if len(profiles) == 0 {
+		return "No profiles found.\n"
+	}
+	rows := make([][]string, 0, len(profiles))
+	for _, p := range profiles {
+		scopes := "-"
+		if p.Loaded {
+			scopes = strings.Join(p.Scopes, ", ")
+			if scopes == "" {
+				scopes = "unknown"
+			}
+		}
+		rows = append(rows, []string{p.Name, p.AuthType, p.Email, profileFlags(p), strings.Join(p.Workspaces, ", "), scopes})
+	}
+	return mdTable([]string{"Profile", "Auth", "Email", "Flags", "Workspaces", "Scopes"}, rows)
+}
+
+func profileMarkdown(p profileInfo) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n", p.Name)
+	fmt.Fprintf(&b, "- Auth: %s\n", p.AuthType)
+	if p.Email != "" {
+		fmt.Fprintf(&b, "- Email: %s\n", p.Email)
+	}
+	if flags := profileFlags(p); flags != "" {
+		fmt.Fprintf(&b, "- Flags: %s\n", flags)
+	}
+	fmt.Fprintf(&b, "- Created: %s\n", mdTime(p.CreatedAt))
+	if len(p.Workspaces) > 0 {
+		fmt.Fprintf(&b, "- Workspaces: %s\n", strings.Join(p.Workspaces, ", "))
+	}
+	scopes := strings.Join(p.Scopes, ", ")
+	if scopes == "" {
+		scopes = "unknown (all tools offered)"
+	}
+	fmt.Fprintf(&b, "- Scopes: %s\n", scopes)
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// progressReporter sends MCP progress notifications for a tool call. It is a
+// no-op when the caller did not supply a progress token.
+type progressReporter struct {
+	ctx     context.Context
+	session *mcp.ServerSession
+	token   any
+}
+
+func newProgress(ctx context.Context, req *mcp.CallToolRequest) *progressReporter {
+	p := &progressReporter{ctx: ctx}
+	if req != nil && req.Session != nil && req.Params != nil {
+		p.session = req.Session
+		p.token = req.Params.GetProgressToken()
+	}
+	return p
+}
+
+// report sends a notification. total may be 0 when unknown.
+func (p *progressReporter) report(progress, total float64, message string) {
+	if p.token == nil || p.session == nil {
+		return
+	}
+	// Progress is best effort; a client that went away will see the result (or not) regardless.
+	_ = p.session.NotifyProgress(p.ctx, &mcp.ProgressNotificationParams{
+		ProgressToken: p.token,
+		Progress:      progress,
+		Total:         total,
+		Message:       message,
+	})
+}
+
+// pages returns an OnPage callback reporting items fetched across pages.
+func (p *progressReporter) pages(noun string) func(fetched, total int) {
+	if p.token == nil {
+		return nil
+	}
+	page := 0
+	return func(fetched, total int) {
+		page++
+		p.report(float64(fetched), float64(total), fmt.Sprintf("fetched %d %s (page %d)", fetched, noun, page))
+	}
+}
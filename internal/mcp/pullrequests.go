@@ -2,34 +2,49 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/zach-snell/bbkt/internal/bitbucket"
 )
 
 type ManagePullRequestsArgs struct {
-	Action            string `json:"action" jsonschema:"Action to perform: 'list', 'get', 'create', 'update', 'merge', 'approve', 'unapprove', 'decline', 'get-diff', 'get-diffstat', 'get-commits'" jsonschema_enum:"list,get,create,update,merge,approve,unapprove,decline,get-diff,get-diffstat,get-commits"`
-	Workspace         string `json:"workspace" jsonschema:"Workspace slug"`
-	RepoSlug          string `json:"repo_slug" jsonschema:"Repository slug"`
-	PRID              int    `json:"pr_id,omitempty" jsonschema:"Pull request ID"`
-	Title             string `json:"title,omitempty" jsonschema:"Title of the pull request (for 'create', 'update')"`
-	Description       string `json:"description,omitempty" jsonschema:"Description of the pull request (for 'create', 'update')"`
-	SourceBranch      string `json:"source_branch,omitempty" jsonschema:"Source branch name (for 'create')"`
-	DestinationBranch string `json:"destination_branch,omitempty" jsonschema:"Destination branch name (for 'create')"`
-	CloseSourceBranch bool   `json:"close_source_branch,omitempty" jsonschema:"Close source branch (for 'create', 'merge')"`
-	Draft             bool   `json:"draft,omitempty" jsonschema:"Create as a draft PR (for 'create')"`
-	Message           string `json:"message,omitempty" jsonschema:"Commit message (for 'merge')"`
-	MergeStrategy     string `json:"merge_strategy,omitempty" jsonschema:"Merge strategy (e.g. merge_commit, squash, fast_forward) (for 'merge')"`
-	State             string `json:"state,omitempty" jsonschema:"Filter by state (MERGED, SUPERSEDED, OPEN, DECLINED) (for 'list')"`
-	Query             string `json:"query,omitempty" jsonschema:"Filter query (for 'list')"`
-	Page              int    `json:"page,omitempty" jsonschema:"Page number"`
-	Pagelen           int    `json:"pagelen,omitempty" jsonschema:"Results per page"`
+	Action              string   `json:"action" jsonschema:"Action to perform: 'list', 'get', 'create', 'update', 'merge', 'approve', 'unapprove', 'request-changes', 'remove-request-changes', 'decline', 'get-diff', 'get-diffstat', 'get-commits', 'get-activity', 'statuses', 'merge-when-ready'" jsonschema_enum:"list,get,create,update,merge,merge-when-ready,approve,unapprove,request-changes,remove-request-changes,decline,get-diff,get-diffstat,get-commits,get-activity,statuses"`
+	Workspace           string   `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug            string   `json:"repo_slug" jsonschema:"Repository slug"`
+	PRID                int      `json:"pr_id,omitempty" jsonschema:"Pull request ID"`
+	Title               string   `json:"title,omitempty" jsonschema:"Title of the pull request (for 'create', 'update')"`
+	Description         string   `json:"description,omitempty" jsonschema:"Description of the pull request (for 'create', 'update')"`
+	SourceBranch        string   `json:"source_branch,omitempty" jsonschema:"Source branch name (for 'create')"`
+	DestinationBranch   string   `json:"destination_branch,omitempty" jsonschema:"Destination branch name (for 'create')"`
+	CloseSourceBranch   bool     `json:"close_source_branch,omitempty" jsonschema:"Close source branch (for 'create', 'merge', 'merge-when-ready')"`
+	Draft               bool     `json:"draft,omitempty" jsonschema:"Create as a draft PR (for 'create')"`
+	GenerateDescription bool     `json:"generate_description,omitempty" jsonschema:"Draft the title and description from the branch's commits and diffstat using the client's model, following the repository's PR template; any title or description given is used as guidance (for 'create')"`
+	Reviewers           []string `json:"reviewers,omitempty" jsonschema:"Reviewers by account ID, {UUID} or nickname (for 'create')"`
+	AddReviewers        []string `json:"add_reviewers,omitempty" jsonschema:"Reviewers to add by account ID, {UUID} or nickname (for 'update')"`
+	RemoveReviewers     []string `json:"remove_reviewers,omitempty" jsonschema:"Reviewers to remove by account ID, {UUID} or nickname (for 'update')"`
+	Preview             bool     `json:"preview,omitempty" jsonschema:"Return the drafted title and description without creating the pull request (for 'create' with generate_description), or run the merge checks without merging (for 'merge')"`
+	Message             string   `json:"message,omitempty" jsonschema:"Commit message (for 'merge', 'merge-when-ready')"`
+	MergeStrategy       string   `json:"merge_strategy,omitempty" jsonschema:"Merge strategy: merge_commit, squash, fast_forward, squash_fast_forward, rebase_fast_forward or rebase_merge; must be allowed on the destination branch, defaults to its default (for 'merge', 'merge-when-ready')"`
+	Timeout             int      `json:"timeout,omitempty" jsonschema:"Seconds to wait for the merge checks to pass (default 1800, max 3600) (for 'merge-when-ready')"`
+	PollInterval        int      `json:"poll_interval,omitempty" jsonschema:"Seconds between checks (default 30, min 10) (for 'merge-when-ready')"`
+	State               string   `json:"state,omitempty" jsonschema:"Filter by state (MERGED, SUPERSEDED, OPEN, DECLINED) (for 'list')"`
+	Query               string   `json:"query,omitempty" jsonschema:"Filter query (for 'list')"`
+	Page                int      `json:"page,omitempty" jsonschema:"Page number"`
+	Pagelen             int      `json:"pagelen,omitempty" jsonschema:"Results per page"`
+	All                 bool     `json:"all,omitempty" jsonschema:"Fetch every page of results, reporting progress per page (for 'list')"`
+	Context             *int     `json:"context,omitempty" jsonschema:"Lines of context around each change (default 3) (for 'get-diff')"`
+	IgnoreWhitespace    bool     `json:"ignore_whitespace,omitempty" jsonschema:"Ignore whitespace-only changes (for 'get-diff')"`
+	ExcludeBinary       bool     `json:"exclude_binary,omitempty" jsonschema:"Leave binary files out of the diff (for 'get-diff')"`
+	Paths               []string `json:"paths,omitempty" jsonschema:"Only include files matching these paths, directories or globs such as 'src/api/*.go' (for 'get-diff')"`
+	PerFile             bool     `json:"per_file,omitempty" jsonschema:"Split the diff into per-file chunks with a diffstat; with format 'json' returns the chunks as an array (for 'get-diff')"`
+	Cursor              string   `json:"cursor,omitempty" jsonschema:"Continuation cursor from a truncated 'get-diff' response"`
+	Format              string   `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
 // ManagePullRequestsHandler handles the consolidated pull request operations.
-func ManagePullRequestsHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolRequest, ManagePullRequestsArgs) (*mcp.CallToolResult, any, error) {
+func ManagePullRequestsHandler(c *bitbucket.Client, budget int) func(context.Context, *mcp.CallToolRequest, ManagePullRequestsArgs) (*mcp.CallToolResult, any, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, args ManagePullRequestsArgs) (*mcp.CallToolResult, any, error) {
 		switch args.Action {
 		case "list":
@@ -40,12 +55,13 @@ func ManagePullRequestsHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 				Pagelen:   args.Pagelen,
 				Page:      args.Page,
 				Query:     args.Query,
+				All:       args.All,
+				OnPage:    newProgress(ctx, req).pages("pull requests"),
 			})
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list pull requests: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return prsMarkdown(result) })
 
 		case "get":
 			if args.PRID == 0 {
@@ -59,28 +75,44 @@ func ManagePullRequestsHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get pull request: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(pr, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, pr, func() string { return prMarkdown(pr) })
 
 		case "create":
-			if args.Title == "" || args.SourceBranch == "" {
-				return ToolResultError("title and source_branch are required for 'create' action"), nil, nil
+			if args.SourceBranch == "" {
+				return ToolResultError("source_branch is required for 'create' action"), nil, nil
+			}
+			title, description, dest := args.Title, args.Description, args.DestinationBranch
+			if args.GenerateDescription {
+				draft, err := draftPullRequest(ctx, req, c, args)
+				if err != nil {
+					return ToolResultError(fmt.Sprintf("failed to draft pull request: %v", err)), nil, nil
+				}
+				if args.Preview {
+					return render(args.Format, draft, func() string { return draftMarkdown(draft) })
+				}
+				title, description, dest = draft.Title, draft.Description, draft.Destination
+			} else if args.Title == "" {
+				return ToolResultError("title is required for 'create' action unless generate_description is set"), nil, nil
+			}
+			reviewers, err := c.ResolveReviewers(args.Workspace, args.Reviewers)
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to resolve reviewers: %v", err)), nil, nil
 			}
 			pr, err := c.CreatePullRequest(bitbucket.CreatePullRequestArgs{
 				Workspace:         args.Workspace,
 				RepoSlug:          args.RepoSlug,
-				Title:             args.Title,
-				Description:       args.Description,
+				Title:             title,
+				Description:       description,
 				SourceBranch:      args.SourceBranch,
-				DestinationBranch: args.DestinationBranch,
+				DestinationBranch: dest,
 				CloseSourceBranch: args.CloseSourceBranch,
 				Draft:             args.Draft,
+				Reviewers:         reviewers,
 			})
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to create pull request: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(pr, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, pr, func() string { return prMarkdown(pr) })
 
 		case "update":
 			if args.PRID == 0 {
@@ -95,36 +127,78 @@ func ManagePullRequestsHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 				description = &args.Description
 			}
 
+			var reviewers *[]bitbucket.ReviewerRef
+			if len(args.AddReviewers) > 0 || len(args.RemoveReviewers) > 0 {
+				add, err := c.ResolveReviewers(args.Workspace, args.AddReviewers)
+				if err != nil {
+					return ToolResultError(fmt.Sprintf("failed to resolve reviewers: %v", err)), nil, nil
+				}
+				remove, err := c.ResolveReviewers(args.Workspace, args.RemoveReviewers)
+				if err != nil {
+					return ToolResultError(fmt.Sprintf("failed to resolve reviewers: %v", err)), nil, nil
+				}
+				current, err := c.GetPullRequest(bitbucket.GetPullRequestArgs{
+					Workspace: args.Workspace,
+					RepoSlug:  args.RepoSlug,
+					PRID:      args.PRID,
+				})
+				if err != nil {
+					return ToolResultError(fmt.Sprintf("failed to get pull request: %v", err)), nil, nil
+				}
+				changed := bitbucket.ChangeReviewers(current.Reviewers, add, remove)
+				reviewers = &changed
+			}
+
 			pr, err := c.UpdatePullRequest(bitbucket.UpdatePullRequestArgs{
 				Workspace:   args.Workspace,
 				RepoSlug:    args.RepoSlug,
 				PRID:        args.PRID,
 				Title:       title,
 				Description: description,
+				Reviewers:   reviewers,
 			})
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to update pull request: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(pr, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, pr, func() string { return prMarkdown(pr) })
 
 		case "merge":
 			if args.PRID == 0 {
 				return ToolResultError("pr_id is required for 'merge' action"), nil, nil
 			}
-			pr, err := c.MergePullRequest(bitbucket.MergePullRequestArgs{
+			result, err := c.MergePullRequest(ctx, bitbucket.MergePullRequestArgs{
 				Workspace:         args.Workspace,
 				RepoSlug:          args.RepoSlug,
 				PRID:              args.PRID,
 				Message:           args.Message,
 				CloseSourceBranch: args.CloseSourceBranch,
 				MergeStrategy:     args.MergeStrategy,
+				CheckOnly:         args.Preview,
 			})
 			if err != nil {
-				return ToolResultError(fmt.Sprintf("failed to merge pull request: %v", err)), nil, nil
+				msg := fmt.Sprintf("failed to merge pull request: %v", err)
+				if result != nil {
+					msg += "\n\n" + mergeMarkdown(result)
+					// Returned for the audit log, which skips blocked previews.
+					return ToolResultError(msg), result, nil
+				}
+				return ToolResultError(msg), nil, nil
+			}
+			return render(args.Format, result, func() string { return mergeMarkdown(result) })
+
+		case "merge-when-ready":
+			if args.PRID == 0 {
+				return ToolResultError("pr_id is required for 'merge-when-ready' action"), nil, nil
+			}
+			result, err := mergeWhenReady(ctx, c, args, newProgress(ctx, req))
+			if err != nil {
+				msg := fmt.Sprintf("did not merge pull request: %v", err)
+				if result != nil {
+					msg += "\n\n" + mergeMarkdown(result)
+				}
+				return ToolResultError(msg), nil, nil
 			}
-			data, _ := json.MarshalIndent(pr, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return mergeMarkdown(result) })
 
 		case "approve":
 			if args.PRID == 0 {
@@ -152,6 +226,32 @@ func ManagePullRequestsHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 			}
 			return ToolResultText(fmt.Sprintf("Pull request #%d unapproved", args.PRID)), nil, nil
 
+		case "request-changes":
+			if args.PRID == 0 {
+				return ToolResultError("pr_id is required for 'request-changes' action"), nil, nil
+			}
+			if err := c.RequestChangesPullRequest(bitbucket.PullRequestActionArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				PRID:      args.PRID,
+			}); err != nil {
+				return ToolResultError(fmt.Sprintf("failed to request changes: %v", err)), nil, nil
+			}
+			return ToolResultText(fmt.Sprintf("Changes requested on pull request #%d", args.PRID)), nil, nil
+
+		case "remove-request-changes":
+			if args.PRID == 0 {
+				return ToolResultError("pr_id is required for 'remove-request-changes' action"), nil, nil
+			}
+			if err := c.RemoveRequestChangesPullRequest(bitbucket.PullRequestActionArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				PRID:      args.PRID,
+			}); err != nil {
+				return ToolResultError(fmt.Sprintf("failed to remove change request: %v", err)), nil, nil
+			}
+			return ToolResultText(fmt.Sprintf("Change request removed from pull request #%d", args.PRID)), nil, nil
+
 		case "decline":
 			if args.PRID == 0 {
 				return ToolResultError("pr_id is required for 'decline' action"), nil, nil
@@ -169,15 +269,34 @@ func ManagePullRequestsHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 			if args.PRID == 0 {
 				return ToolResultError("pr_id is required for 'get-diff' action"), nil, nil
 			}
+			opts := bitbucket.DiffOptions{
+				Context:          args.Context,
+				IgnoreWhitespace: args.IgnoreWhitespace,
+				ExcludeBinary:    args.ExcludeBinary,
+				Paths:            args.Paths,
+			}
 			raw, err := c.GetPRDiff(bitbucket.PullRequestActionArgs{
 				Workspace: args.Workspace,
 				RepoSlug:  args.RepoSlug,
 				PRID:      args.PRID,
-			})
+			}, opts)
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get PR diff: %v", err)), nil, nil
 			}
-			return ToolResultText(string(raw)), nil, nil
+			if len(raw) == 0 && len(args.Paths) > 0 {
+				return ToolResultText("No changed files match the given paths."), nil, nil
+			}
+			text := string(raw)
+			if args.PerFile {
+				files := bitbucket.ParseDiff(raw)
+				text = renderText(args.Format, files, func() string { return fileDiffsMarkdown(files) })
+			}
+			key := fmt.Sprintf("pr-diff:%s/%s/%d:%s:%t:%s", args.Workspace, args.RepoSlug, args.PRID, opts, args.PerFile, args.Format)
+			text, err = budgetText(text, key, args.Cursor, budget, boundaryDiffFile)
+			if err != nil {
+				return ToolResultError(err.Error()), nil, nil
+			}
+			return ToolResultText(text), nil, nil
 
 		case "get-diffstat":
 			if args.PRID == 0 {
@@ -191,8 +310,7 @@ func ManagePullRequestsHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get PR diffstat: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return diffstatMarkdown(result) })
 
 		case "get-commits":
 			if args.PRID == 0 {
@@ -206,11 +324,71 @@ func ManagePullRequestsHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list PR commits: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return commitsMarkdown(result) })
+
+		case "statuses":
+			if args.PRID == 0 {
+				return ToolResultError("pr_id is required for 'statuses' action"), nil, nil
+			}
+			result, err := c.ListPRStatuses(bitbucket.PullRequestActionArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				PRID:      args.PRID,
+			})
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to list PR statuses: %v", err)), nil, nil
+			}
+			return render(args.Format, result, func() string { return statusesMarkdown(result) })
+
+		case "get-activity":
+			if args.PRID == 0 {
+				return ToolResultError("pr_id is required for 'get-activity' action"), nil, nil
+			}
+			result, err := c.GetPRActivity(bitbucket.PullRequestActionArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				PRID:      args.PRID,
+			})
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to get PR activity: %v", err)), nil, nil
+			}
+			events := bitbucket.PRTimeline(result.Values)
+			return render(args.Format, events, func() string { return timelineMarkdown(events, time.Now()) })
 
 		default:
 			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil
 		}
 	}
 }
+
+// mergeWhenReady waits for a pull request's merge checks to pass and merges
+// it, sending a progress notification after every poll so clients do not time
+// out the call.
+func mergeWhenReady(ctx context.Context, c *bitbucket.Client, args ManagePullRequestsArgs, progress *progressReporter) (*bitbucket.MergeResult, error) {
+	timeout := time.Duration(args.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = bitbucket.DefaultMergeWhenReadyTimeout
+	}
+	timeout = min(timeout, time.Hour)
+	interval := max(time.Duration(args.PollInterval)*time.Second, 10*time.Second)
+	if args.PollInterval == 0 {
+		interval = bitbucket.DefaultMergeWhenReadyInterval
+	}
+
+	start := time.Now()
+	return c.MergeWhenReady(ctx, bitbucket.MergeWhenReadyArgs{
+		MergePullRequestArgs: bitbucket.MergePullRequestArgs{
+			Workspace:         args.Workspace,
+			RepoSlug:          args.RepoSlug,
+			PRID:              args.PRID,
+			Message:           args.Message,
+			CloseSourceBranch: args.CloseSourceBranch,
+			MergeStrategy:     args.MergeStrategy,
+		},
+		Timeout:  timeout,
+		Interval: interval,
+		OnPoll: func(r *bitbucket.MergeResult) {
+			progress.report(time.Since(start).Seconds(), timeout.Seconds(), fmt.Sprintf("PR #%d: %s", args.PRID, r.Summary()))
+		},
+	})
+}
@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// Refresher re-evaluates the token scopes of running servers and adds or
+// removes tools to match, so a server follows profile switches and scope
+// changes without a restart.
+type Refresher struct {
+	mu         sync.Mutex
+	registries []*toolRegistry
+}
+
+func (f *Refresher) add(r *toolRegistry) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.registries = append(f.registries, r)
+}
+
+// Refresh switches every server to creds, or only re-fetches the scopes of
+// the current credentials when creds is nil, and updates the tool lists.
+func (f *Refresher) Refresh(creds *bitbucket.Credentials) {
+	f.mu.Lock()
+	registries := append([]*toolRegistry(nil), f.registries...)
+	f.mu.Unlock()
+
+	for _, r := range registries {
+		r.refresh(creds)
+	}
+}
+
+func (r *toolRegistry) refresh(creds *bitbucket.Credentials) {
+	if r.opts.Profiles != nil {
+		r.opts.Profiles.reset()
+	}
+	if creds != nil {
+		r.client.SetCredentials(creds)
+	} else {
+		r.client.ResetScopes()
+	}
+
+	scopes, err := r.client.Scopes()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to refresh token scopes: %v\n", err)
+		if creds == nil {
+			// Same credentials: keep the tool set that was last known to be right.
+			return
+		}
+		// New credentials fall back to the full tool set, as at startup.
+	}
+
+	r.mu.Lock()
+	if creds != nil {
+		r.profile, r.user = creds.ProfileName, creds.Email
+	}
+	r.tokenScopes = scopes
+	r.mu.Unlock()
+	r.resync()
+}
+
+// resync re-evaluates which tools and actions are exposed and logs changes.
+func (r *toolRegistry) resync() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added, updated, removed := r.sync()
+	if len(added) > 0 {
+		fmt.Fprintf(os.Stderr, "Enabled tools: %s\n", strings.Join(added, ", "))
+	}
+	if len(updated) > 0 {
+		fmt.Fprintf(os.Stderr, "Updated tool actions: %s\n", strings.Join(updated, ", "))
+	}
+	if len(removed) > 0 {
+		fmt.Fprintf(os.Stderr, "Disabled tools: %s\n", strings.Join(removed, ", "))
+	}
+}
@@ -0,0 +1,645 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// Output formats selected with the format argument.
+const (
+	// formatMarkdown renders compact Markdown tables and summaries (the default).
+	formatMarkdown = "markdown"
+	// formatJSON renders the Bitbucket object as indented JSON.
+	formatJSON = "json"
+	// formatRaw renders the Bitbucket object as compact JSON.
+	formatRaw = "raw"
+)
+
+// checkFormat returns a refusal message for an unknown format argument.
+func checkFormat(format string) string {
+	switch format {
+	case "", formatMarkdown, formatJSON, formatRaw:
+		return ""
+	}
+	return fmt.Sprintf("invalid format '%s': expected 'markdown', 'json' or 'raw'", format)
+}
+
+// render builds a tool result for v in the requested format. markdown renders
+// the compact form; when it is nil, Markdown output falls back to JSON. v is
+// also returned as the handler output so the audit log can record the IDs of
+// the object, even though the client only sees the rendered text.
+func render(format string, v any, markdown func() string) (*mcp.CallToolResult, any, error) {
+	return ToolResultText(renderText(format, v, markdown)), v, nil
+}
+
+// renderText returns the text render would send, for callers that still need
+// to truncate it to the response budget.
+func renderText(format string, v any, markdown func() string) string {
+	switch {
+	case format == formatRaw:
+		data, _ := json.Marshal(v)
+		return string(data)
+	case format == formatJSON || markdown == nil:
+		data, _ := json.MarshalIndent(v, "", "  ")
+		return string(data)
+	default:
+		return markdown()
+	}
+}
+
+// --- Markdown helpers ---
+
+// mdTable renders a Markdown table, escaping cell content.
+func mdTable(header []string, rows [][]string) string {
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(header, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat("---|", len(header)) + "\n")
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = mdCell(c)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	return b.String()
+}
+
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\r", "")
+	s = strings.ReplaceAll(s, "\n", " ")
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// mdPage summarises the pagination state below a table.
+func mdPage[T any](p *bitbucket.Paginated[T], noun string) string {
+	if len(p.Values) == 0 {
+		return "No " + noun + " found.\n"
+	}
+	s := fmt.Sprintf("\n%d %s", len(p.Values), noun)
+	if p.Size > 0 {
+		s += fmt.Sprintf(" of %d", p.Size)
+	}
+	if p.Page > 0 {
+		s += fmt.Sprintf(" (page %d)", p.Page)
+	}
+	if p.Next != "" {
+		s += fmt.Sprintf("; more available with page=%d", max(p.Page, 1)+1)
+	}
+	return s + "\n"
+}
+
+func mdTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+func mdTimePtr(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return mdTime(*t)
+}
+
+// mdTruncate shortens s to a single line of at most n runes.
+func mdTruncate(s string, n int) string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r", ""))
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i] + " …"
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+func mdDuration(secs int) string {
+	if secs == 0 {
+		return "-"
+	}
+	return (time.Duration(secs) * time.Second).String()
+}
+
+func mdUser(u *bitbucket.User) string {
+	if u == nil {
+		return "-"
+	}
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	return u.Nickname
+}
+
+func mdAuthor(a *bitbucket.Author) string {
+	if a == nil {
+		return "-"
+	}
+	if a.User != nil {
+		return mdUser(a.User)
+	}
+	if name, _, ok := strings.Cut(a.Raw, " <"); ok {
+		return name
+	}
+	return a.Raw
+}
+
+func mdHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+func mdYesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// --- Workspaces & repositories ---
+
+func workspacesMarkdown(p *bitbucket.Paginated[bitbucket.Workspace]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, w := range p.Values {
+		rows = append(rows, []string{w.Slug, w.Name, mdYesNo(w.IsPrivate)})
+	}
+	return mdTable([]string{"Slug", "Name", "Private"}, rows) + mdPage(p, "workspaces")
+}
+
+func workspaceMarkdown(w *bitbucket.Workspace) string {
+	return fmt.Sprintf("**%s** (`%s`)\n- UUID: %s\n- Private: %s\n", w.Name, w.Slug, w.UUID, mdYesNo(w.IsPrivate))
+}
+
+func reposMarkdown(p *bitbucket.Paginated[bitbucket.Repository]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, r := range p.Values {
+		rows = append(rows, []string{r.Slug, r.Language, mdYesNo(r.IsPrivate), mdTime(r.UpdatedOn), mdTruncate(r.Description, 60)})
+	}
+	return mdTable([]string{"Repository", "Language", "Private", "Updated", "Description"}, rows) + mdPage(p, "repositories")
+}
+
+func repoMarkdown(r *bitbucket.Repository) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**\n", r.FullName)
+	if r.Description != "" {
+		fmt.Fprintf(&b, "%s\n", r.Description)
+	}
+	fmt.Fprintf(&b, "- Private: %s\n", mdYesNo(r.IsPrivate))
+	if r.Language != "" {
+		fmt.Fprintf(&b, "- Language: %s\n", r.Language)
+	}
+	if r.MainBranch != nil {
+		fmt.Fprintf(&b, "- Main branch: %s\n", r.MainBranch.Name)
+	}
+	if r.Project != nil {
+		fmt.Fprintf(&b, "- Project: %s (%s)\n", r.Project.Name, r.Project.Key)
+	}
+	fmt.Fprintf(&b, "- Created: %s\n- Updated: %s\n", mdTime(r.CreatedOn), mdTime(r.UpdatedOn))
+	return b.String()
+}
+
+// --- Refs & commits ---
+
+func refRow(name string, target *bitbucket.Commit) []string {
+	if target == nil {
+		return []string{name, "-", "-", "-", "-"}
+	}
+	return []string{name, mdHash(target.Hash), mdTime(target.Date), mdAuthor(target.Author), mdTruncate(target.Message, 60)}
+}
+
+func branchesMarkdown(p *bitbucket.Paginated[bitbucket.Branch]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, br := range p.Values {
+		rows = append(rows, refRow(br.Name, br.Target))
+	}
+	return mdTable([]string{"Branch", "Commit", "Date", "Author", "Message"}, rows) + mdPage(p, "branches")
+}
+
+func tagsMarkdown(p *bitbucket.Paginated[bitbucket.Tag]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, t := range p.Values {
+		rows = append(rows, refRow(t.Name, t.Target))
+	}
+	return mdTable([]string{"Tag", "Commit", "Date", "Author", "Message"}, rows) + mdPage(p, "tags")
+}
+
+func refMarkdown(kind, name string, target *bitbucket.Commit) string {
+	if target == nil {
+		return fmt.Sprintf("%s `%s`\n", kind, name)
+	}
+	return fmt.Sprintf("%s `%s` at `%s`: %s\n", kind, name, mdHash(target.Hash), mdTruncate(target.Message, 72))
+}
+
+func commitsMarkdown(p *bitbucket.Paginated[bitbucket.Commit]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, c := range p.Values {
+		rows = append(rows, []string{mdHash(c.Hash), mdTime(c.Date), mdAuthor(c.Author), mdTruncate(c.Message, 72)})
+	}
+	return mdTable([]string{"Commit", "Date", "Author", "Message"}, rows) + mdPage(p, "commits")
+}
+
+func commitMarkdown(c *bitbucket.Commit) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Commit %s**\n- Author: %s\n- Date: %s\n", c.Hash, mdAuthor(c.Author), mdTime(c.Date))
+	if len(c.Parents) > 0 {
+		parents := make([]string, len(c.Parents))
+		for i, p := range c.Parents {
+			parents[i] = mdHash(p.Hash)
+		}
+		fmt.Fprintf(&b, "- Parents: %s\n", strings.Join(parents, ", "))
+	}
+	fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(c.Message))
+	return b.String()
+}
+
+func timelineMarkdown(events []bitbucket.PRTimelineEvent, now time.Time) string {
+	if len(events) == 0 {
+		return "No activity found.\n"
+	}
+	rows := make([][]string, 0, len(events))
+	for _, e := range events {
+		rows = append(rows, []string{mdTime(e.Date) + " (" + bitbucket.TimeAgo(e.Date, now) + ")", mdUser(e.Actor), e.Action, mdTruncate(e.Detail, 80)})
+	}
+	return mdTable([]string{"When", "Who", "What", "Detail"}, rows)
+}
+
+func statusesMarkdown(p *bitbucket.Paginated[bitbucket.CommitStatus]) string {
+	if len(p.Values) == 0 {
+		return "No build statuses reported.\n"
+	}
+	rows := make([][]string, 0, len(p.Values))
+	for _, s := range p.Values {
+		rows = append(rows, []string{s.State, s.Key, s.Name, mdTruncate(s.Description, 60), mdTime(s.UpdatedOn), s.URL})
+	}
+	return mdTable([]string{"State", "Key", "Name", "Description", "Updated", "URL"}, rows) + mdPage(p, "statuses")
+}
+
+func statusMarkdown(s *bitbucket.CommitStatus) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** (%s): %s\n", s.Name, s.Key, s.State)
+	if s.Description != "" {
+		fmt.Fprintf(&b, "- Description: %s\n", s.Description)
+	}
+	if s.Refname != "" {
+		fmt.Fprintf(&b, "- Ref: %s\n", s.Refname)
+	}
+	fmt.Fprintf(&b, "- URL: %s\n- Updated: %s\n", s.URL, mdTime(s.UpdatedOn))
+	return b.String()
+}
+
+func diffstatMarkdown(p *bitbucket.Paginated[bitbucket.DiffStat]) string {
+	rows := make([][]string, 0, len(p.Values))
+	added, removed := 0, 0
+	for _, d := range p.Values {
+		path := "-"
+		switch {
+		case d.Old != nil && d.New != nil && d.Old.Path != d.New.Path:
+			path = d.Old.Path + " → " + d.New.Path
+		case d.New != nil:
+			path = d.New.Path
+		case d.Old != nil:
+			path = d.Old.Path
+		}
+		rows = append(rows, []string{d.Status, path, fmt.Sprintf("+%d -%d", d.LinesAdded, d.LinesRemoved)})
+		added += d.LinesAdded
+		removed += d.LinesRemoved
+	}
+	return mdTable([]string{"Status", "File", "Lines"}, rows) +
+		fmt.Sprintf("\nTotal: +%d -%d\n", added, removed) + mdPage(p, "files")
+}
+
+// fileDiffsMarkdown renders a diffstat of the files followed by each file's
+// diff, so a budgeted response still opens with the overview.
+func fileDiffsMarkdown(files []bitbucket.FileDiff) string {
+	if len(files) == 0 {
+		return "No changes.\n"
+	}
+	rows := make([][]string, 0, len(files))
+	added, removed := 0, 0
+	for _, f := range files {
+		path := f.Path()
+		if f.Status == "renamed" {
+			path = f.OldPath + " → " + f.NewPath
+		}
+		lines := fmt.Sprintf("+%d -%d", f.LinesAdded, f.LinesRemoved)
+		if f.Binary {
+			lines = "binary"
+		}
+		rows = append(rows, []string{f.Status, path, lines})
+		added += f.LinesAdded
+		removed += f.LinesRemoved
+	}
+
+	var b strings.Builder
+	b.WriteString(mdTable([]string{"Status", "File", "Lines"}, rows))
+	fmt.Fprintf(&b, "\nTotal: %d files, +%d -%d\n\n", len(files), added, removed)
+	for _, f := range files {
+		b.WriteString(f.Text)
+	}
+	return b.String()
+}
+
+// --- Pull requests ---
+
+func prsMarkdown(p *bitbucket.Paginated[bitbucket.PullRequest]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, pr := range p.Values {
+		state := pr.State
+		if pr.Draft {
+			state += " (draft)"
+		}
+		rows = append(rows, []string{
+			fmt.Sprintf("#%d", pr.ID), mdTruncate(pr.Title, 60), mdUser(pr.Author),
+			prBranches(&pr), state, mdTime(pr.UpdatedOn),
+		})
+	}
+	return mdTable([]string{"ID", "Title", "Author", "Branches", "State", "Updated"}, rows) + mdPage(p, "pull requests")
+}
+
+func prBranches(pr *bitbucket.PullRequest) string {
+	branch := func(e bitbucket.PREndpoint) string {
+		if e.Branch == nil {
+			return "?"
+		}
+		return e.Branch.Name
+	}
+	return branch(pr.Source) + " → " + branch(pr.Destination)
+}
+
+func mergeMarkdown(r *bitbucket.MergeResult) string {
+	var b strings.Builder
+	pr := r.PullRequest
+	switch {
+	case r.Merged:
+		fmt.Fprintf(&b, "**Merged PR #%d** with %s", pr.ID, r.Strategy)
+		if pr.MergeCommit != nil {
+			fmt.Fprintf(&b, " as %s", mdHash(pr.MergeCommit.Hash))
+		}
+		b.WriteString("\n")
+	case len(r.Blocking()) > 0:
+		fmt.Fprintf(&b, "**PR #%d can't be merged yet** (strategy: %s)\n", pr.ID, r.Strategy)
+	default:
+		fmt.Fprintf(&b, "**PR #%d is ready to merge** with %s\n", pr.ID, r.Strategy)
+	}
+	if len(r.AllowedStrategies) > 0 {
+		fmt.Fprintf(&b, "- Allowed strategies: %s\n", strings.Join(r.AllowedStrategies, ", "))
+	}
+	if r.Note != "" {
+		fmt.Fprintf(&b, "- Note: %s\n", r.Note)
+	}
+
+	rows := make([][]string, 0, len(r.Checks))
+	for _, c := range r.Checks {
+		status := "pass"
+		if !c.Passed {
+			status = "FAIL"
+		}
+		enforced := "advisory"
+		if c.Required {
+			enforced = "required"
+		}
+		rows = append(rows, []string{c.Name, status, enforced, c.Detail})
+	}
+	return b.String() + "\n" + mdTable([]string{"Check", "Status", "Enforced", "Detail"}, rows)
+}
+
+func prMarkdown(pr *bitbucket.PullRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**#%d %s**\n", pr.ID, pr.Title)
+	state := pr.State
+	if pr.Draft {
+		state += " (draft)"
+	}
+	fmt.Fprintf(&b, "- State: %s\n- Author: %s\n- Branches: %s\n", state, mdUser(pr.Author), prBranches(pr))
+
+	var reviewers []string
+	for _, p := range pr.Participants {
+		if p.Role != "REVIEWER" && !p.Approved && p.State == "" {
+			continue
+		}
+		r := mdUser(p.User)
+		switch {
+		case p.Approved:
+			r += " (approved)"
+		case p.State == "changes_requested":
+			r += " (changes requested)"
+		}
+		reviewers = append(reviewers, r)
+	}
+	if len(reviewers) == 0 {
+		for _, u := range pr.Reviewers {
+			reviewers = append(reviewers, mdUser(&u))
+		}
+	}
+	if len(reviewers) > 0 {
+		fmt.Fprintf(&b, "- Reviewers: %s\n", strings.Join(reviewers, ", "))
+	}
+	fmt.Fprintf(&b, "- Comments: %d, open tasks: %d\n", pr.CommentCount, pr.TaskCount)
+	if pr.MergeCommit != nil {
+		fmt.Fprintf(&b, "- Merge commit: %s\n", mdHash(pr.MergeCommit.Hash))
+	}
+	fmt.Fprintf(&b, "- Created: %s, updated: %s\n", mdTime(pr.CreatedOn), mdTime(pr.UpdatedOn))
+	if d := strings.TrimSpace(pr.Description); d != "" {
+		fmt.Fprintf(&b, "\n%s\n", d)
+	}
+	return b.String()
+}
+
+// --- PR comments ---
+
+// commentsMarkdown renders comments as threads: each top-level comment
+// followed by its replies, indented by depth.
+func commentsMarkdown(p *bitbucket.Paginated[bitbucket.PRComment]) string {
+	if len(p.Values) == 0 {
+		return mdPage(p, "comments")
+	}
+
+	present := make(map[int]bool, len(p.Values))
+	children := make(map[int][]bitbucket.PRComment)
+	for _, c := range p.Values {
+		present[c.ID] = true
+	}
+	var roots []bitbucket.PRComment
+	for _, c := range p.Values {
+		// Replies whose parent is on another page are shown as roots.
+		if c.Parent != nil && present[c.Parent.ID] {
+			children[c.Parent.ID] = append(children[c.Parent.ID], c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+
+	var b strings.Builder
+	var walk func(c bitbucket.PRComment, depth int)
+	walk = func(c bitbucket.PRComment, depth int) {
+		b.WriteString(strings.Repeat("  ", depth) + "- " + commentLine(&c) + "\n")
+		for _, reply := range children[c.ID] {
+			walk(reply, depth+1)
+		}
+	}
+	for _, c := range roots {
+		walk(c, 0)
+	}
+	b.WriteString(mdPage(p, "comments"))
+	return b.String()
+}
+
+func commentLine(c *bitbucket.PRComment) string {
+	var meta []string
+	meta = append(meta, fmt.Sprintf("#%d", c.ID), mdUser(c.User), mdTime(c.CreatedOn))
+	if c.Inline != nil {
+		loc := c.Inline.Path
+		if c.Inline.To != nil {
+			loc += fmt.Sprintf(":%d", *c.Inline.To)
+		} else if c.Inline.From != nil {
+			loc += fmt.Sprintf(":%d (old)", *c.Inline.From)
+		}
+		meta = append(meta, loc)
+	}
+	if c.Pending {
+		meta = append(meta, "pending")
+	}
+	body := strings.TrimSpace(c.Content.Raw)
+	if c.Deleted {
+		body = "[deleted]"
+	}
+	return "**" + strings.Join(meta, " · ") + "**: " + strings.ReplaceAll(body, "\n", " ")
+}
+
+func commentMarkdown(c *bitbucket.PRComment) string {
+	return commentLine(c) + "\n"
+}
+
+// --- PR tasks ---
+
+func taskLine(t *bitbucket.PRTask) string {
+	box := "[ ]"
+	if t.State == bitbucket.TaskStateResolved {
+		box = "[x]"
+	}
+	meta := []string{fmt.Sprintf("#%d", t.ID), mdUser(t.Creator)}
+	if t.Comment != nil && t.Comment.ID != 0 {
+		meta = append(meta, fmt.Sprintf("on comment #%d", t.Comment.ID))
+	}
+	if t.ResolvedBy != nil {
+		meta = append(meta, "resolved by "+mdUser(t.ResolvedBy))
+	}
+	return box + " **" + strings.Join(meta, " · ") + "**: " + strings.ReplaceAll(strings.TrimSpace(t.Content.Raw), "\n", " ")
+}
+
+func tasksMarkdown(p *bitbucket.Paginated[bitbucket.PRTask]) string {
+	var b strings.Builder
+	open := 0
+	for _, t := range p.Values {
+		b.WriteString("- " + taskLine(&t) + "\n")
+		if t.State != bitbucket.TaskStateResolved {
+			open++
+		}
+	}
+	if len(p.Values) > 0 {
+		fmt.Fprintf(&b, "\n%d open on this page.", open)
+	}
+	b.WriteString(mdPage(p, "tasks"))
+	return b.String()
+}
+
+func taskMarkdown(t *bitbucket.PRTask) string {
+	return taskLine(t) + "\n"
+}
+
+// --- Source ---
+
+func treeMarkdown(p *bitbucket.Paginated[bitbucket.TreeEntry]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, e := range p.Values {
+		kind, size := "file", fmt.Sprint(e.Size)
+		if e.Type == "commit_directory" {
+			kind, size = "dir", "-"
+		}
+		rows = append(rows, []string{kind, e.Path, size})
+	}
+	return mdTable([]string{"Type", "Path", "Size"}, rows) + mdPage(p, "entries")
+}
+
+// --- Pipelines ---
+
+func pipelineRef(p *bitbucket.Pipeline) string {
+	if p.Target == nil || p.Target.RefName == "" {
+		return "-"
+	}
+	return p.Target.RefName
+}
+
+func pipelinesMarkdown(p *bitbucket.Paginated[bitbucket.Pipeline]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, pipe := range p.Values {
+		rows = append(rows, []string{
+			fmt.Sprintf("#%d", pipe.BuildNumber), pipelineStatus(&pipe), pipelineRef(&pipe),
+			mdUser(pipe.Creator), mdTime(pipe.CreatedOn), mdDuration(pipe.DurationSecs), pipe.UUID,
+		})
+	}
+	return mdTable([]string{"Build", "Status", "Ref", "Creator", "Started", "Duration", "UUID"}, rows) + mdPage(p, "pipelines")
+}
+
+func pipelineMarkdown(p *bitbucket.Pipeline) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Pipeline #%d** %s\n- UUID: %s\n- Ref: %s\n", p.BuildNumber, pipelineStatus(p), p.UUID, pipelineRef(p))
+	if p.Creator != nil {
+		fmt.Fprintf(&b, "- Creator: %s\n", mdUser(p.Creator))
+	}
+	if p.TriggerName != "" {
+		fmt.Fprintf(&b, "- Trigger: %s\n", p.TriggerName)
+	}
+	fmt.Fprintf(&b, "- Started: %s, completed: %s, duration: %s\n", mdTime(p.CreatedOn), mdTimePtr(p.CompletedOn), mdDuration(p.DurationSecs))
+	return b.String()
+}
+
+func stepsMarkdown(p *bitbucket.Paginated[bitbucket.PipelineStep]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, s := range p.Values {
+		status := "-"
+		if s.State != nil {
+			status = s.State.Name
+			if s.State.Result != nil {
+				status = s.State.Result.Name
+			}
+		}
+		rows = append(rows, []string{s.Name, status, mdTimePtr(s.StartedOn), mdDuration(s.DurationSecs), s.UUID})
+	}
+	return mdTable([]string{"Step", "Status", "Started", "Duration", "UUID"}, rows) + mdPage(p, "steps")
+}
+
+// --- Issues ---
+
+func issuesMarkdown(p *bitbucket.Paginated[bitbucket.Issue]) string {
+	rows := make([][]string, 0, len(p.Values))
+	for _, i := range p.Values {
+		rows = append(rows, []string{
+			fmt.Sprintf("#%d", i.ID), mdTruncate(i.Title, 60), i.State, i.Kind, i.Priority, mdUser(i.Assignee), mdTime(i.UpdatedOn),
+		})
+	}
+	return mdTable([]string{"ID", "Title", "State", "Kind", "Priority", "Assignee", "Updated"}, rows) + mdPage(p, "issues")
+}
+
+func issueMarkdown(i *bitbucket.Issue) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**#%d %s**\n- State: %s, kind: %s, priority: %s\n- Reporter: %s, assignee: %s\n- Created: %s, updated: %s\n",
+		i.ID, i.Title, i.State, i.Kind, i.Priority, mdUser(i.Reporter), mdUser(i.Assignee), mdTime(i.CreatedOn), mdTime(i.UpdatedOn))
+	if c := strings.TrimSpace(i.Content.Raw); c != "" {
+		fmt.Fprintf(&b, "\n%s\n", c)
+	}
+	return b.String()
+}
@@ -0,0 +1,189 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+func TestMarkdownHelpers(t *testing.T) {
+	when := time.Date(2026, 3, 4, 15, 4, 0, 0, time.UTC)
+	tests := []struct {
+		name, got, want string
+	}{
+		{"cell escapes pipes and newlines", mdCell("a|b\r\nc"), `a\|b c`},
+		{"empty cell", mdCell(""), "-"},
+		{"time", mdTime(when), "2026-03-04 15:04"},
+		{"zero time", mdTime(time.Time{}), "-"},
+		{"nil time", mdTimePtr(nil), "-"},
+		{"truncate keeps short text", mdTruncate("  short  ", 10), "short"},
+		{"truncate cuts runes", mdTruncate("héllo wörld", 6), "héllo…"},
+		{"truncate keeps first line", mdTruncate("first\nsecond", 20), "first …"},
+		{"zero duration", mdDuration(0), "-"},
+		{"duration", mdDuration(90), "1m30s"},
+		{"nil user", mdUser(nil), "-"},
+		{"user display name", mdUser(&bitbucket.User{DisplayName: "Ada", Nickname: "ada"}), "Ada"},
+		{"user nickname", mdUser(&bitbucket.User{Nickname: "ada"}), "ada"},
+		{"nil author", mdAuthor(nil), "-"},
+		{"author user", mdAuthor(&bitbucket.Author{User: &bitbucket.User{DisplayName: "Ada"}, Raw: "x <x@y>"}), "Ada"},
+		{"author raw", mdAuthor(&bitbucket.Author{Raw: "Ada Lovelace <ada@example.com>"}), "Ada Lovelace"},
+		{"author raw without email", mdAuthor(&bitbucket.Author{Raw: "ada"}), "ada"},
+		{"short hash", mdHash("abc"), "abc"},
+		{"long hash", mdHash("0123456789abcdef"), "0123456789ab"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestMdPage(t *testing.T) {
+	tests := []struct {
+		name string
+		page *bitbucket.Paginated[int]
+		want string
+	}{
+		{"empty", &bitbucket.Paginated[int]{}, "No items found.\n"},
+		{"single page", &bitbucket.Paginated[int]{Values: []int{1, 2}}, "\n2 items\n"},
+		{"more pages", &bitbucket.Paginated[int]{Values: []int{1}, Size: 3, Page: 1, Next: "x"}, "\n1 items of 3 (page 1); more available with page=2\n"},
+		{"next without page", &bitbucket.Paginated[int]{Values: []int{1}, Next: "x"}, "\n1 items; more available with page=2\n"},
+	}
+	for _, tt := range tests {
+		if got := mdPage(tt.page, "items"); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestRenderers checks that each renderer copes with missing nested objects
+// and empty fields, and renders the parts that are set.
+func TestRenderers(t *testing.T) {
+	to := 12
+	when := time.Date(2026, 3, 4, 15, 4, 0, 0, time.UTC)
+	ada := &bitbucket.User{DisplayName: "Ada"}
+
+	tests := []struct {
+		name     string
+		got      string
+		contains []string
+		excludes []string
+	}{
+		{"empty repository", repoMarkdown(&bitbucket.Repository{}),
+			[]string{"- Private: no", "- Created: -"}, []string{"Main branch", "Project", "Language"}},
+		{"repository", repoMarkdown(&bitbucket.Repository{FullName: "acme/api", MainBranch: &bitbucket.Branch{Name: "main"}, Project: &bitbucket.Project{Name: "Core", Key: "CORE"}}),
+			[]string{"**acme/api**", "- Main branch: main", "- Project: Core (CORE)"}, nil},
+		{"empty repository list", reposMarkdown(&bitbucket.Paginated[bitbucket.Repository]{}),
+			[]string{"| Repository |", "No repositories found."}, nil},
+		{"branch without target", branchesMarkdown(&bitbucket.Paginated[bitbucket.Branch]{Values: []bitbucket.Branch{{Name: "main"}}}),
+			[]string{"| main | - | - | - | - |"}, nil},
+		{"ref without target", refMarkdown("Branch", "main", nil), []string{"Branch `main`\n"}, []string{" at "}},
+		{"commit without author or parents", commitMarkdown(&bitbucket.Commit{Hash: "abc", Message: "fix\n"}),
+			[]string{"**Commit abc**", "- Author: -", "- Date: -", "\nfix\n"}, []string{"Parents"}},
+		{"commit with parents", commitMarkdown(&bitbucket.Commit{Hash: "abc", Parents: []bitbucket.Commit{{Hash: "0123456789abcdef"}, {Hash: "def"}}}),
+			[]string{"- Parents: 0123456789ab, def"}, nil},
+		{"no activity", timelineMarkdown(nil, when), []string{"No activity found."}, nil},
+		{"activity without actor", timelineMarkdown([]bitbucket.PRTimelineEvent{{Date: when.Add(-3 * time.Hour), Action: "merged"}}, when),
+			[]string{"2026-03-04 12:04 (3h ago) | - | merged | - |"}, nil},
+		{"no statuses", statusesMarkdown(&bitbucket.Paginated[bitbucket.CommitStatus]{}), []string{"No build statuses reported."}, nil},
+		{"status without description", statusMarkdown(&bitbucket.CommitStatus{Name: "ci", Key: "k", State: "SUCCESSFUL"}),
+			[]string{"**ci** (k): SUCCESSFUL", "- Updated: -"}, []string{"Description", "Ref:"}},
+		{"diffstat of added and renamed files", diffstatMarkdown(&bitbucket.Paginated[bitbucket.DiffStat]{Values: []bitbucket.DiffStat{
+			{Status: "added", New: &bitbucket.DiffStatRef{Path: "new.go"}, LinesAdded: 3},
+			{Status: "renamed", Old: &bitbucket.DiffStatRef{Path: "a.go"}, New: &bitbucket.DiffStatRef{Path: "b.go"}, LinesRemoved: 1},
+			{Status: "modified"},
+		}}), []string{"| added | new.go | +3 -0 |", "| renamed | a.go → b.go | +0 -1 |", "| modified | - | +0 -0 |", "Total: +3 -1"}, nil},
+		{"no file diffs", fileDiffsMarkdown(nil), []string{"No changes."}, nil},
+		{"binary file diff", fileDiffsMarkdown([]bitbucket.FileDiff{{NewPath: "logo.png", Status: "added", Binary: true, Text: "diff --git\n"}}),
+			[]string{"| added | logo.png | binary |", "Total: 1 files, +0 -0", "diff --git\n"}, nil},
+		{"pull request without branches or author", prsMarkdown(&bitbucket.Paginated[bitbucket.PullRequest]{Values: []bitbucket.PullRequest{{ID: 1, Title: "t", State: "OPEN", Draft: true}}}),
+			[]string{"| #1 | t | - | ? → ? | OPEN (draft) | - |"}, nil},
+		{"empty pull request", prMarkdown(&bitbucket.PullRequest{ID: 7}),
+			[]string{"**#7 **", "- Author: -", "- Branches: ? → ?", "- Comments: 0, open tasks: 0"}, []string{"Reviewers", "Merge commit"}},
+		{"pull request reviews", prMarkdown(&bitbucket.PullRequest{ID: 7, Participants: []bitbucket.Participant{
+			{User: ada, Role: "REVIEWER", Approved: true},
+			{Role: "REVIEWER", State: "changes_requested"},
+			{User: &bitbucket.User{DisplayName: "Commenter"}, Role: "PARTICIPANT"},
+		}}), []string{"- Reviewers: Ada (approved), - (changes requested)"}, []string{"Commenter"}},
+		{"pull request reviewers without participants", prMarkdown(&bitbucket.PullRequest{ID: 7, Reviewers: []bitbucket.User{*ada}}),
+			[]string{"- Reviewers: Ada"}, nil},
+		{"merge check without restrictions", mergeMarkdown(&bitbucket.MergeResult{PullRequest: &bitbucket.PullRequest{ID: 3}, Strategy: "squash",
+			Checks: []bitbucket.MergeCheck{{Name: "builds", Detail: "0 of 1 successful"}}}),
+			[]string{"**PR #3 is ready to merge** with squash", "| builds | FAIL | advisory | 0 of 1 successful |"}, []string{"Allowed strategies", "Note"}},
+		{"blocked merge", mergeMarkdown(&bitbucket.MergeResult{PullRequest: &bitbucket.PullRequest{ID: 3}, Strategy: "squash",
+			Checks: []bitbucket.MergeCheck{{Name: "approvals", Required: true}}}),
+			[]string{"**PR #3 can't be merged yet**", "| approvals | FAIL | required | - |"}, nil},
+		{"no comments", commentsMarkdown(&bitbucket.Paginated[bitbucket.PRComment]{}), []string{"No comments found."}, nil},
+		{"comment threads", commentsMarkdown(&bitbucket.Paginated[bitbucket.PRComment]{Values: []bitbucket.PRComment{
+			{ID: 1, Content: bitbucket.Content{Raw: "root"}},
+			{ID: 2, Parent: &bitbucket.ParentRef{ID: 1}, Content: bitbucket.Content{Raw: "reply"}},
+			{ID: 3, Parent: &bitbucket.ParentRef{ID: 99}, Content: bitbucket.Content{Raw: "orphan"}},
+		}}), []string{"- **#1 · - · -**: root\n  - **#2 · - · -**: reply\n- **#3 · - · -**: orphan\n"}, nil},
+		{"deleted inline comment", commentMarkdown(&bitbucket.PRComment{ID: 4, User: ada, Deleted: true, Pending: true,
+			Inline: &bitbucket.Inline{Path: "a.go", To: &to}, Content: bitbucket.Content{Raw: "gone"}}),
+			[]string{"**#4 · Ada · - · a.go:12 · pending**: [deleted]"}, []string{"gone"}},
+		{"comment on removed line", commentMarkdown(&bitbucket.PRComment{ID: 5, Inline: &bitbucket.Inline{Path: "a.go", From: &to}}),
+			[]string{"a.go:12 (old)**: \n"}, nil},
+		{"task without creator or comment", taskMarkdown(&bitbucket.PRTask{ID: 9, Content: bitbucket.Content{Raw: "do\nit"}}),
+			[]string{"[ ] **#9 · -**: do it"}, []string{"on comment", "resolved by"}},
+		{"resolved task", taskMarkdown(&bitbucket.PRTask{ID: 9, State: bitbucket.TaskStateResolved, Comment: &bitbucket.ParentRef{ID: 2}, ResolvedBy: ada}),
+			[]string{"[x] **#9 · - · on comment #2 · resolved by Ada**"}, nil},
+		{"no tasks", tasksMarkdown(&bitbucket.Paginated[bitbucket.PRTask]{}), []string{"No tasks found."}, []string{"open on this page"}},
+		{"directory entry", treeMarkdown(&bitbucket.Paginated[bitbucket.TreeEntry]{Values: []bitbucket.TreeEntry{{Path: "src", Type: "commit_directory"}}}),
+			[]string{"| dir | src | - |"}, nil},
+		{"pipeline without state or target", pipelineMarkdown(&bitbucket.Pipeline{BuildNumber: 5}),
+			[]string{"**Pipeline #5** UNKNOWN", "- Ref: -", "completed: -, duration: -"}, []string{"Creator", "Trigger"}},
+		{"pipeline list", pipelinesMarkdown(&bitbucket.Paginated[bitbucket.Pipeline]{Values: []bitbucket.Pipeline{{BuildNumber: 5, Target: &bitbucket.PipeTarget{}}}}),
+			[]string{"| #5 | UNKNOWN | - | - | - | - | - |"}, nil},
+		{"step without state", stepsMarkdown(&bitbucket.Paginated[bitbucket.PipelineStep]{Values: []bitbucket.PipelineStep{{Name: "build"}}}),
+			[]string{"| build | - | - | - | - |"}, nil},
+		{"finished step", stepsMarkdown(&bitbucket.Paginated[bitbucket.PipelineStep]{Values: []bitbucket.PipelineStep{{Name: "build",
+			State: &bitbucket.PipeState{Name: "COMPLETED", Result: &bitbucket.PipeResult{Name: "SUCCESSFUL"}}}}}),
+			[]string{"| build | SUCCESSFUL |"}, nil},
+		{"issue without content", issueMarkdown(&bitbucket.Issue{ID: 2, Title: "bug"}),
+			[]string{"**#2 bug**", "- Reporter: -, assignee: -"}, []string{"\n\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, want := range tt.contains {
+				if !strings.Contains(tt.got, want) {
+					t.Errorf("missing %q in:\n%s", want, tt.got)
+				}
+			}
+			for _, unwanted := range tt.excludes {
+				if strings.Contains(tt.got, unwanted) {
+					t.Errorf("unexpected %q in:\n%s", unwanted, tt.got)
+				}
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	v := map[string]int{"id": 1}
+	markdown := func() string { return "**1**" }
+	tests := []struct {
+		format   string
+		markdown func() string
+		want     string
+	}{
+		{"", markdown, "**1**"},
+		{formatMarkdown, markdown, "**1**"},
+		{formatMarkdown, nil, "{\n  \"id\": 1\n}"},
+		{formatJSON, markdown, "{\n  \"id\": 1\n}"},
+		{formatRaw, markdown, `{"id":1}`},
+	}
+	for _, tt := range tests {
+		res, out, _ := render(tt.format, v, tt.markdown)
+		if got := res.Content[0].(*mcp.TextContent).Text; got != tt.want {
+			t.Errorf("render(%q) = %q, want %q", tt.format, got, tt.want)
+		}
+		if out == nil {
+			t.Errorf("render(%q) returned no output for the audit log", tt.format)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -22,10 +21,12 @@ type ManageRepositoriesArgs struct {
 	Query       string `json:"query,omitempty" jsonschema:"Bitbucket query filter (e.g. name~'myrepo')"`
 	Role        string `json:"role,omitempty" jsonschema:"Filter by role: owner, admin, contributor, member"`
 	Sort        string `json:"sort,omitempty" jsonschema:"Sort field (e.g. -updated_on)"`
+	All         bool   `json:"all,omitempty" jsonschema:"Fetch every page of results, reporting progress per page (for 'list')"`
+	Format      string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
 // ManageRepositoriesHandler handles the consolidated repository operations.
-func ManageRepositoriesHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolRequest, ManageRepositoriesArgs) (*mcp.CallToolResult, any, error) {
+func ManageRepositoriesHandler(c *bitbucket.Client, allow *Allowlist) func(context.Context, *mcp.CallToolRequest, ManageRepositoriesArgs) (*mcp.CallToolResult, any, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, args ManageRepositoriesArgs) (*mcp.CallToolResult, any, error) {
 		switch args.Action {
 		case "list":
@@ -36,12 +37,16 @@ func ManageRepositoriesHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 				Query:     args.Query,
 				Role:      args.Role,
 				Sort:      args.Sort,
+				All:       args.All || allow != nil,
+				OnPage:    newProgress(ctx, req).pages("repositories"),
 			})
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list repositories: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			if allow != nil {
+				filterPage(result, func(r bitbucket.Repository) bool { return allow.Allows(args.Workspace, r.Slug) })
+			}
+			return render(args.Format, result, func() string { return reposMarkdown(result) })
 
 		case "get":
 			if args.Workspace == "" || args.RepoSlug == "" {
@@ -54,8 +59,7 @@ func ManageRepositoriesHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get repository: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(repo, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, repo, func() string { return repoMarkdown(repo) })
 
 		case "create":
 			if args.Workspace == "" || args.RepoSlug == "" {
@@ -72,8 +76,7 @@ func ManageRepositoriesHandler(c *bitbucket.Client) func(context.Context, *mcp.C
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to create repository: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(repo, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, repo, func() string { return repoMarkdown(repo) })
 
 		case "delete":
 			if args.Workspace == "" || args.RepoSlug == "" {
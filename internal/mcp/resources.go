@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/yosida95/uritemplate/v3"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// resourceTemplates are the read-only Bitbucket objects clients can attach as
+// context. Their workspace, repo_slug, pr_id and ref variables are filled in
+// by the completion handler.
+var resourceTemplates = []struct {
+	tmpl mcp.ResourceTemplate
+	read func(c *bitbucket.Client, v uritemplate.Values) (string, error)
+}{
+	{
+		tmpl: mcp.ResourceTemplate{
+			Name:        "repository",
+			Title:       "Repository",
+			URITemplate: "bitbucket://{workspace}/{repo_slug}",
+			Description: "Summary of a Bitbucket repository",
+			MIMEType:    "text/markdown",
+		},
+		read: func(c *bitbucket.Client, v uritemplate.Values) (string, error) {
+			repo, err := c.GetRepository(bitbucket.GetRepositoryArgs{
+				Workspace: v.Get("workspace").String(),
+				RepoSlug:  v.Get("repo_slug").String(),
+			})
+			if err != nil {
+				return "", err
+			}
+			return repoMarkdown(repo), nil
+		},
+	},
+	{
+		tmpl: mcp.ResourceTemplate{
+			Name:        "pull_request",
+			Title:       "Pull request",
+			URITemplate: "bitbucket://{workspace}/{repo_slug}/pull-requests/{pr_id}",
+			Description: "Summary and description of a pull request",
+			MIMEType:    "text/markdown",
+		},
+		read: func(c *bitbucket.Client, v uritemplate.Values) (string, error) {
+			id, err := strconv.Atoi(v.Get("pr_id").String())
+			if err != nil {
+				return "", fmt.Errorf("invalid pr_id %q", v.Get("pr_id").String())
+			}
+			pr, err := c.GetPullRequest(bitbucket.GetPullRequestArgs{
+				Workspace: v.Get("workspace").String(),
+				RepoSlug:  v.Get("repo_slug").String(),
+				PRID:      id,
+			})
+			if err != nil {
+				return "", err
+			}
+			return prMarkdown(pr), nil
+		},
+	},
+	{
+		tmpl: mcp.ResourceTemplate{
+			Name:        "file",
+			Title:       "Repository file",
+			URITemplate: "bitbucket://{workspace}/{repo_slug}/src/{ref}/{+path}",
+			Description: "Contents of a file at a branch, tag or commit",
+			MIMEType:    "text/plain",
+		},
+		read: func(c *bitbucket.Client, v uritemplate.Values) (string, error) {
+			raw, _, err := c.GetFileContent(bitbucket.GetFileContentArgs{
+				Workspace: v.Get("workspace").String(),
+				RepoSlug:  v.Get("repo_slug").String(),
+				Ref:       v.Get("ref").String(),
+				Path:      v.Get("path").String(),
+			})
+			if err != nil {
+				return "", err
+			}
+			return string(raw), nil
+		},
+	},
+}
+
+// registerResources adds the resource templates, bounded by the allowlist.
+func registerResources(s *mcp.Server, c *bitbucket.Client, opts *Options) {
+	for _, rt := range resourceTemplates {
+		tmpl := rt.tmpl
+		matcher := uritemplate.MustNew(tmpl.URITemplate)
+		read := rt.read
+
+		s.AddResourceTemplate(&tmpl, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+			uri := req.Params.URI
+			v := matcher.Match(uri)
+			if v == nil {
+				return nil, mcp.ResourceNotFoundError(uri)
+			}
+			target := toolTarget{Workspace: v.Get("workspace").String(), RepoSlug: v.Get("repo_slug").String()}
+			if msg := checkAllowlist(opts.Allowlist, target); msg != "" {
+				return nil, fmt.Errorf("%s", msg)
+			}
+
+			text, err := read(c, v)
+			if err != nil {
+				if strings.HasPrefix(err.Error(), "API error 404") {
+					return nil, mcp.ResourceNotFoundError(uri)
+				}
+				return nil, err
+			}
+			return &mcp.ReadResourceResult{
+				Contents: []*mcp.ResourceContents{{URI: uri, MIMEType: tmpl.MIMEType, Text: text}},
+			}, nil
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// draftMaxCommits and draftMaxFiles bound how much of the branch is shown to
+// the client's model when drafting a pull request.
+const (
+	draftMaxCommits = 50
+	draftMaxFiles   = 100
+)
+
+// draftSystemPrompt instructs the client's model how to answer a draft request.
+const draftSystemPrompt = `You write pull request titles and descriptions for Bitbucket.
+Reply with a single JSON object {"title": "...", "description": "..."} and nothing else.
+The title is one imperative line under 72 characters. The description is Markdown
+explaining what changed and why; when a template is given, fill in its sections
+instead of inventing new ones.`
+
+// prDraft is a generated pull request title and description.
+type prDraft struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Source      string `json:"source_branch"`
+	Destination string `json:"destination_branch"`
+	Model       string `json:"model,omitempty"`
+}
+
+func supportsSampling(req *mcp.CallToolRequest) bool {
+	if req == nil || req.Session == nil {
+		return false
+	}
+	params := req.Session.InitializeParams()
+	return params != nil && params.Capabilities != nil && params.Capabilities.Sampling != nil
+}
+
+// draftPullRequest asks the client's model, through MCP sampling, for a title
+// and description covering the commits and diffstat between the branches.
+// An empty destination resolves to the repository's main branch.
+func draftPullRequest(ctx context.Context, req *mcp.CallToolRequest, c *bitbucket.Client, args ManagePullRequestsArgs) (*prDraft, error) {
+	if !supportsSampling(req) {
+		return nil, fmt.Errorf("the connected client does not support sampling; pass title and description explicitly")
+	}
+
+	dest := args.DestinationBranch
+	if dest == "" {
+		repo, err := c.GetRepository(bitbucket.GetRepositoryArgs{Workspace: args.Workspace, RepoSlug: args.RepoSlug})
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve destination branch: %w", err)
+		}
+		if repo.MainBranch == nil || repo.MainBranch.Name == "" {
+			return nil, fmt.Errorf("repository has no main branch; pass destination_branch")
+		}
+		dest = repo.MainBranch.Name
+	}
+
+	commits, err := c.ListCommits(bitbucket.ListCommitsArgs{
+		Workspace: args.Workspace,
+		RepoSlug:  args.RepoSlug,
+		Include:   args.SourceBranch,
+		Exclude:   dest,
+		Pagelen:   draftMaxCommits,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list commits: %w", err)
+	}
+	if len(commits.Values) == 0 {
+		return nil, fmt.Errorf("%s has no commits that are not on %s", args.SourceBranch, dest)
+	}
+
+	diffstat, err := c.GetDiffStat(bitbucket.GetDiffStatArgs{
+		Workspace: args.Workspace,
+		RepoSlug:  args.RepoSlug,
+		Spec:      args.SourceBranch + ".." + dest,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diffstat: %w", err)
+	}
+
+	template, err := c.GetPullRequestTemplate(args.Workspace, args.RepoSlug, dest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pull request template: %w", err)
+	}
+
+	res, err := req.Session.CreateMessage(ctx, &mcp.CreateMessageParams{
+		SystemPrompt: draftSystemPrompt,
+		MaxTokens:    2000,
+		Messages: []*mcp.SamplingMessage{{
+			Role:    "user",
+			Content: &mcp.TextContent{Text: draftPrompt(args, dest, commits.Values, diffstat.Values, template)},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sampling request failed: %w", err)
+	}
+	text, ok := res.Content.(*mcp.TextContent)
+	if !ok {
+		return nil, fmt.Errorf("sampling returned non-text content")
+	}
+
+	draft, err := parseDraft(text.Text)
+	if err != nil {
+		return nil, err
+	}
+	draft.Source = args.SourceBranch
+	draft.Destination = dest
+	draft.Model = res.Model
+	return draft, nil
+}
+
+// draftPrompt describes the branch to the model. A caller-supplied title or
+// description is passed along as guidance.
+func draftPrompt(args ManagePullRequestsArgs, dest string, commits []bitbucket.Commit, files []bitbucket.DiffStat, template string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Repository: %s/%s\nMerging %s into %s.\n", args.Workspace, args.RepoSlug, args.SourceBranch, dest)
+	if args.Title != "" {
+		fmt.Fprintf(&b, "\nThe author suggested this title: %s\n", args.Title)
+	}
+	if args.Description != "" {
+		fmt.Fprintf(&b, "\nThe author's notes:\n%s\n", args.Description)
+	}
+
+	fmt.Fprintf(&b, "\nCommits (newest first):\n")
+	for _, cm := range commits {
+		fmt.Fprintf(&b, "- %s\n", strings.TrimSpace(cm.Message))
+	}
+
+	fmt.Fprintf(&b, "\nFiles changed:\n")
+	for i, f := range files {
+		if i == draftMaxFiles {
+			fmt.Fprintf(&b, "- ... and %d more\n", len(files)-draftMaxFiles)
+			break
+		}
+		path := ""
+		if f.New != nil {
+			path = f.New.Path
+		} else if f.Old != nil {
+			path = f.Old.Path
+		}
+		fmt.Fprintf(&b, "- %s %s (+%d -%d)\n", f.Status, path, f.LinesAdded, f.LinesRemoved)
+	}
+
+	if template != "" {
+		fmt.Fprintf(&b, "\nRepository pull request template:\n%s\n", template)
+	}
+	return b.String()
+}
+
+// parseDraft reads the JSON object out of the model's reply, tolerating a
+// surrounding code fence or prose.
+func parseDraft(text string) (*prDraft, error) {
+	start, end := strings.Index(text, "{"), strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("sampling reply did not contain a JSON object")
+	}
+	var d prDraft
+	if err := json.Unmarshal([]byte(text[start:end+1]), &d); err != nil {
+		return nil, fmt.Errorf("failed to parse sampling reply: %w", err)
+	}
+	d.Title = strings.TrimSpace(d.Title)
+	if d.Title == "" {
+		return nil, fmt.Errorf("sampling reply had no title")
+	}
+	return &d, nil
+}
+
+// draftMarkdown renders a draft for preview.
+func draftMarkdown(d *prDraft) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Draft: %s**\n", d.Title)
+	fmt.Fprintf(&b, "- Branches: %s → %s\n", d.Source, d.Destination)
+	if d.Model != "" {
+		fmt.Fprintf(&b, "- Drafted by: %s\n", d.Model)
+	}
+	fmt.Fprintf(&b, "\n%s\n", strings.TrimSpace(d.Description))
+	return b.String()
+}
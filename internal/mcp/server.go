@@ -2,37 +2,86 @@ package mcp
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"sync"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/audit"
 	"github.com/zach-snell/bbkt/internal/bitbucket"
 	"github.com/zach-snell/bbkt/internal/version"
 )
 
+// Options configures server-wide policy for the MCP server.
+// The zero value applies no restrictions.
+type Options struct {
+	// Allowlist bounds the workspaces and repositories tools may act on.
+	Allowlist *Allowlist
+	// ConfirmFallback applies to destructive actions when the client cannot elicit
+	// confirmation from the human. Defaults to ConfirmRefuse.
+	ConfirmFallback ConfirmFallback
+	// AuditLog, when set, records every mutating tool call.
+	AuditLog *audit.Logger
+	// Profile and User identify the caller in audit entries.
+	Profile string
+	User    string
+	// Calls, when set, tracks in-flight tool calls so they can be drained on shutdown.
+	Calls *CallTracker
+	// ResponseBudget caps, in bytes, the diff, log and file content returned by a
+	// single call; larger payloads are truncated with a continuation cursor.
+	// Zero disables truncation.
+	ResponseBudget int
+	// Defaults fill in workspace and repo_slug when a tool call omits them.
+	Defaults RepoDefaults
+	// ToolMode selects unified manage_* tools (the default) or one tool per action.
+	ToolMode ToolMode
+	// Refresher, when set, lets the caller re-evaluate token scopes or switch
+	// credentials while the server runs.
+	Refresher *Refresher
+	// Profiles, when set, lets tool calls act as another stored profile through
+	// a profile argument, and adds the manage_profiles tool.
+	Profiles *ProfilePool
+}
+
 // New creates and configures the Bitbucket MCP server with all tools registered.
-func New(username, password, token string) *mcp.Server {
+func New(username, password, token string, opts *Options) *mcp.Server {
 	client := bitbucket.NewClient(username, password, token)
-	return newServer(client)
+	return newServer(client, opts, nil)
 }
 
 // NewFromCredentials creates the MCP server from stored credentials, mapping cached scopes.
-func NewFromCredentials(creds *bitbucket.Credentials) *mcp.Server {
+func NewFromCredentials(creds *bitbucket.Credentials, opts *Options) *mcp.Server {
 	client := bitbucket.NewClientFromCredentials(creds)
-	return newServer(client)
+	return newServer(client, opts, nil)
 }
 
-func newServer(client *bitbucket.Client) *mcp.Server {
+func newServer(client *bitbucket.Client, opts *Options, serverOpts *mcp.ServerOptions) *mcp.Server {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if opts.ConfirmFallback == "" {
+		opts.ConfirmFallback = ConfirmRefuse
+	}
+
+	if serverOpts == nil {
+		serverOpts = &mcp.ServerOptions{}
+	}
+	serverOpts.CompletionHandler = newCompleter(client, opts).complete
+
 	s := mcp.NewServer(
 		&mcp.Implementation{
 			Name:    "bbkt",
 			Version: version.Version,
 		},
-		nil,
+		serverOpts,
 	)
 
-	registerTools(s, client)
+	registerTools(s, client, opts)
+	registerResources(s, client, opts)
 	return s
 }
 
@@ -42,7 +91,7 @@ func getToolRequiredScope(toolName string) []string {
 		return nil
 	case "manage_repositories", "manage_refs", "manage_commits", "manage_source":
 		return []string{"repository"}
-	case "manage_pull_requests", "manage_pr_comments":
+	case "manage_pull_requests", "manage_pr_comments", "manage_pr_tasks":
 		return []string{"pullrequest"}
 	case "manage_pipelines":
 		return []string{"pipeline"}
@@ -52,6 +101,75 @@ func getToolRequiredScope(toolName string) []string {
 	return nil
 }
 
+// writeScopes lists the actions that need more than their tool's read scope.
+var writeScopes = map[string]map[string][]string{
+	"manage_repositories": {
+		"create": {"repository:admin"},
+		"delete": {"repository:delete"},
+	},
+	"manage_refs": {
+		"create-branch": {"repository:write"},
+		"delete-branch": {"repository:write"},
+		"create-tag":    {"repository:write"},
+	},
+	"manage_source": {
+		"write_file":  {"repository:write"},
+		"delete_file": {"repository:write"},
+	},
+	"manage_pull_requests": {
+		"create":                 {"pullrequest:write"},
+		"update":                 {"pullrequest:write"},
+		"merge":                  {"pullrequest:write"},
+		"merge-when-ready":       {"pullrequest:write"},
+		"approve":                {"pullrequest:write"},
+		"unapprove":              {"pullrequest:write"},
+		"request-changes":        {"pullrequest:write"},
+		"remove-request-changes": {"pullrequest:write"},
+		"decline":                {"pullrequest:write"},
+	},
+	"manage_pr_comments": {
+		"create":    {"pullrequest:write"},
+		"update":    {"pullrequest:write"},
+		"delete":    {"pullrequest:write"},
+		"resolve":   {"pullrequest:write"},
+		"unresolve": {"pullrequest:write"},
+	},
+	"manage_pr_tasks": {
+		"create":  {"pullrequest:write"},
+		"update":  {"pullrequest:write"},
+		"resolve": {"pullrequest:write"},
+		"reopen":  {"pullrequest:write"},
+		"delete":  {"pullrequest:write"},
+	},
+	"manage_pipelines": {
+		"trigger": {"pipeline:write"},
+		"stop":    {"pipeline:write"},
+	},
+	"manage_issues": {
+		"create": {"issue:write"},
+		"update": {"issue:write"},
+	},
+}
+
+// getActionRequiredScope returns the scopes needed for one action of a tool.
+func getActionRequiredScope(toolName, action string) []string {
+	if scopes, ok := writeScopes[toolName][action]; ok {
+		return scopes
+	}
+	return getToolRequiredScope(toolName)
+}
+
+// allowedActions returns the subset of actions of toolName the token scopes allow.
+func allowedActions(tokenScopes []string, toolName string, actions []string) []string {
+	var allowed []string
+	for _, a := range actions {
+		if hasRequiredScope(tokenScopes, getActionRequiredScope(toolName, a)) {
+			allowed = append(allowed, a)
+		}
+	}
+	return allowed
+}
+
 func hasRequiredScope(tokenScopes, required []string) bool {
 	if len(required) == 0 {
 		return true
@@ -77,14 +195,17 @@ func hasRequiredScope(tokenScopes, required []string) bool {
 					return true
 				}
 			case "repository:write":
-				if ts == "repository:admin" ||
-					ts == "write:repository:bitbucket" || ts == "admin:repository:bitbucket" {
+				if ts == "write:repository:bitbucket" {
 					return true
 				}
 			case "repository:admin":
 				if ts == "admin:repository:bitbucket" {
 					return true
 				}
+			case "repository:delete":
+				if ts == "delete:repository:bitbucket" {
+					return true
+				}
 			case "pullrequest":
 				if ts == "pullrequest:write" ||
 					ts == "read:pullrequest:bitbucket" || ts == "write:pullrequest:bitbucket" {
@@ -118,18 +239,274 @@ func hasRequiredScope(tokenScopes, required []string) bool {
 	return false
 }
 
-// addTool is a helper function to conditionally register a generic tool handler
-func addTool[In any](s *mcp.Server, disabled map[string]bool, tokenScopes []string, tool mcp.Tool, handler func(context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, any, error)) {
-	if disabled[tool.Name] {
+// toolRegistry carries the server-wide policy applied to every registered tool.
+// Tools are recorded first and exposed by sync, so the exposed set can follow
+// the token scopes as they change.
+type toolRegistry struct {
+	server   *mcp.Server
+	client   *bitbucket.Client
+	disabled map[string]bool
+	opts     *Options
+
+	mu          sync.Mutex
+	tokenScopes []string
+	profile     string
+	user        string
+	tools       []registeredTool
+	// active maps each exposed tool to the actions it currently offers.
+	active map[string][]string
+}
+
+// registeredTool is a tool known to the registry, whether or not it is
+// currently exposed.
+type registeredTool struct {
+	name string
+	// toolName is the unified tool whose scope requirements apply.
+	toolName string
+	// actions are the actions the tool can offer.
+	actions []string
+	// add registers, or re-registers, the tool offering only the given actions.
+	add func(actions []string)
+}
+
+// toolTarget holds the arguments common to every tool, decoded from the raw call
+// so that policy can be enforced without knowing the concrete argument type.
+type toolTarget struct {
+	Action    string `json:"action"`
+	Workspace string `json:"workspace"`
+	RepoSlug  string `json:"repo_slug"`
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	PRID      int    `json:"pr_id"`
+	Format    string `json:"format"`
+	Preview   bool   `json:"preview"`
+	Profile   string `json:"profile"`
+}
+
+// toolHandler is the signature of every tool handler.
+type toolHandler[In any] = func(context.Context, *mcp.CallToolRequest, In) (*mcp.CallToolResult, any, error)
+
+// addTool is a helper function to conditionally register a generic tool handler.
+// newHandler binds the handler to a client, so calls can act as another profile.
+func addTool[In any](r *toolRegistry, tool mcp.Tool, newHandler func(*bitbucket.Client) toolHandler[In]) {
+	if r.disabled[tool.Name] {
+		return
+	}
+	if r.opts.ToolMode == ToolModeGranular {
+		for _, a := range toolActions[tool.Name] {
+			addActionTool(r, tool.Name, a, newHandler)
+		}
 		return
 	}
-	if !hasRequiredScope(tokenScopes, getToolRequiredScope(tool.Name)) {
-		return // Silently drop the tool if the token lacks the required scope
+	var actions []string
+	for _, a := range toolActions[tool.Name] {
+		actions = append(actions, a.Action)
+	}
+	guarded := guardTool(r, tool.Name, newHandler)
+	r.tools = append(r.tools, registeredTool{
+		name:     tool.Name,
+		toolName: tool.Name,
+		actions:  actions,
+		add: func(actions []string) {
+			schema, err := inputSchema[In](tool.Name, r.opts.Defaults)
+			if err != nil {
+				panic(fmt.Sprintf("building schema for %s: %v", tool.Name, err))
+			}
+			restrictActions(schema, actions)
+			if r.profileArg(tool.Name) {
+				addProfileArg(schema)
+			}
+			t := tool
+			t.InputSchema = schema
+			mcp.AddTool(r.server, &t, guarded)
+		},
+	})
+}
+
+// restrictActions limits the action argument of a unified tool's schema to actions.
+func restrictActions(schema *jsonschema.Schema, actions []string) {
+	prop, ok := schema.Properties["action"]
+	if !ok {
+		return
+	}
+	prop.Enum = make([]any, len(actions))
+	quoted := make([]string, len(actions))
+	for i, a := range actions {
+		prop.Enum[i] = a
+		quoted[i] = "'" + a + "'"
+	}
+	prop.Description = "Action to perform: " + strings.Join(quoted, ", ")
+}
+
+// profileArg reports whether toolName takes the profile argument.
+func (r *toolRegistry) profileArg(toolName string) bool {
+	return r.opts.Profiles != nil && toolName != "manage_profiles"
+}
+
+// exposedScopes returns the scopes tools are offered for: the server's own,
+// widened by those of any other profile used so far. The caller must hold r.mu.
+func (r *toolRegistry) exposedScopes() []string {
+	if r.opts.Profiles == nil {
+		return r.tokenScopes
+	}
+	return unionScopes(append([][]string{r.tokenScopes}, r.opts.Profiles.scopeSets()...)...)
+}
+
+// sync exposes the registered tools the token scopes allow, each offering only
+// the permitted actions, and withdraws the rest. The server notifies connected
+// clients when its tool list changes. The caller must hold r.mu.
+func (r *toolRegistry) sync() (added, updated, removed []string) {
+	scopes := r.exposedScopes()
+	for _, t := range r.tools {
+		// Silently drop actions, and whole tools, the token lacks scopes for.
+		allowed := allowedActions(scopes, t.toolName, t.actions)
+		current, exposed := r.active[t.name]
+		switch {
+		case len(allowed) == 0:
+			if exposed {
+				delete(r.active, t.name)
+				removed = append(removed, t.name)
+			}
+		case !exposed:
+			t.add(allowed)
+			r.active[t.name] = allowed
+			added = append(added, t.name)
+		case !slices.Equal(allowed, current):
+			t.add(allowed)
+			r.active[t.name] = allowed
+			updated = append(updated, t.name)
+		}
+	}
+	if len(removed) > 0 {
+		r.server.RemoveTools(removed...)
+	}
+	return added, updated, removed
+}
+
+// guardTool wraps a handler with the checks every tool call must pass before
+// it reaches the Bitbucket API.
+func guardTool[In any](r *toolRegistry, toolName string, newHandler func(*bitbucket.Client) toolHandler[In]) toolHandler[In] {
+	serverHandler := newHandler(r.client)
+	return func(ctx context.Context, req *mcp.CallToolRequest, args In) (*mcp.CallToolResult, any, error) {
+		if r.opts.Calls != nil {
+			if !r.opts.Calls.start() {
+				return ToolResultError("server is shutting down; retry the call shortly"), nil, nil
+			}
+			defer r.opts.Calls.done()
+		}
+
+		defaulted := false
+		if !r.opts.Defaults.IsZero() && req != nil && req.Params != nil {
+			patched, filled, err := fillRepoDefaults(toolName, req.Params.Arguments, r.opts.Defaults)
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil, nil
+			}
+			if filled {
+				if err := json.Unmarshal(patched, &args); err != nil {
+					return ToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil, nil
+				}
+				req.Params.Arguments = patched
+				defaulted = true
+			}
+		}
+
+		var target toolTarget
+		if req != nil && req.Params != nil && len(req.Params.Arguments) > 0 {
+			if err := json.Unmarshal(req.Params.Arguments, &target); err != nil {
+				return ToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil, nil
+			}
+		}
+
+		if msg := checkFormat(target.Format); msg != "" {
+			return ToolResultError(msg), nil, nil
+		}
+
+		handler := serverHandler
+		msg := checkAllowlist(r.opts.Allowlist, target)
+		if msg == "" && r.opts.Profiles != nil {
+			handler, msg = selectProfile(r, toolName, target, newHandler, serverHandler)
+		} else if msg == "" && target.Profile != "" {
+			msg = "this server does not support the profile argument"
+		}
+		if msg == "" {
+			msg = confirmDestructive(ctx, req, r.opts.ConfirmFallback, toolName, target)
+		}
+		if msg != "" {
+			res := ToolResultError(msg)
+			r.recordCall(toolName, target, req, res, nil, audit.OutcomeRefused)
+			return res, nil, nil
+		}
+
+		// Handlers return the Bitbucket object behind a rendered result as out,
+		// for the audit log only: the client already has it in the format it
+		// asked for, so it is not repeated as structured content.
+		res, out, err := handler(ctx, req, args)
+		if err != nil {
+			r.recordCall(toolName, target, req, ToolResultError(err.Error()), nil, audit.OutcomeError)
+		} else {
+			r.recordCall(toolName, target, req, res, out, audit.OutcomeSuccess)
+		}
+		if !r.opts.Defaults.IsZero() {
+			echoTarget(res, target, defaulted)
+		}
+		return res, nil, err
+	}
+}
+
+// selectProfile picks the handler for the profile a call names, or the
+// server's own, and refuses actions that profile's token scopes do not cover.
+// Tools are offered for the union of all profiles' scopes, so this is where a
+// call is held to the scopes of the profile it acts as.
+func selectProfile[In any](r *toolRegistry, toolName string, t toolTarget, newHandler func(*bitbucket.Client) toolHandler[In], serverHandler toolHandler[In]) (toolHandler[In], string) {
+	handler := serverHandler
+	var scopes []string
+	if t.Profile == "" {
+		r.mu.Lock()
+		scopes = r.tokenScopes
+		r.mu.Unlock()
+	} else {
+		pc, err := r.opts.Profiles.get(t.Profile)
+		if err != nil {
+			return nil, fmt.Sprintf("cannot act as profile '%s': %v", t.Profile, err)
+		}
+		handler, scopes = newHandler(pc.client), pc.scopes
+	}
+
+	if !hasRequiredScope(scopes, getActionRequiredScope(toolName, t.Action)) {
+		profile := t.Profile
+		if profile == "" {
+			profile = r.currentProfile()
+		}
+		return nil, fmt.Sprintf("profile '%s' lacks the token scopes for %s '%s'", profile, toolName, t.Action)
 	}
-	mcp.AddTool(s, &tool, handler)
+	return handler, ""
+}
+
+// currentProfile returns the profile the server itself acts as.
+func (r *toolRegistry) currentProfile() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.profile
 }
 
-func registerTools(s *mcp.Server, c *bitbucket.Client) {
+// checkAllowlist returns a refusal message when the target falls outside the allowlist.
+func checkAllowlist(a *Allowlist, t toolTarget) string {
+	switch {
+	case t.Workspace == "" && t.RepoSlug == "":
+		return ""
+	case t.RepoSlug == "":
+		if !a.AllowsWorkspace(t.Workspace) {
+			return fmt.Sprintf("access to workspace '%s' is not permitted by the server allowlist", t.Workspace)
+		}
+	default:
+		if !a.Allows(t.Workspace, t.RepoSlug) {
+			return fmt.Sprintf("access to repository '%s/%s' is not permitted by the server allowlist", t.Workspace, t.RepoSlug)
+		}
+	}
+	return ""
+}
+
+func registerTools(s *mcp.Server, c *bitbucket.Client, opts *Options) {
 	disabledToolsEnv := os.Getenv("BITBUCKET_DISABLED_TOOLS")
 	disabled := make(map[string]bool)
 	if disabledToolsEnv != "" {
@@ -143,57 +520,114 @@ func registerTools(s *mcp.Server, c *bitbucket.Client) {
 		fmt.Fprintf(os.Stderr, "Warning: failed to fetch token scopes for introspection: %v\n", err)
 	}
 
+	r := &toolRegistry{
+		server:      s,
+		client:      c,
+		disabled:    disabled,
+		opts:        opts,
+		tokenScopes: tokenScopes,
+		profile:     opts.Profile,
+		user:        opts.User,
+		active:      make(map[string][]string),
+	}
+
 	// ─── Workspaces ──────────────────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_workspaces",
 		Description: "Unified tool for getting and listing Bitbucket workspaces",
-	}, ManageWorkspacesHandler(c))
+	}, func(c *bitbucket.Client) toolHandler[ManageWorkspacesArgs] {
+		return ManageWorkspacesHandler(c, opts.Allowlist)
+	})
 
 	// ─── Repositories ────────────────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_repositories",
 		Description: "Unified tool for listing, getting, creating, and deleting repositories",
-	}, ManageRepositoriesHandler(c))
+	}, func(c *bitbucket.Client) toolHandler[ManageRepositoriesArgs] {
+		return ManageRepositoriesHandler(c, opts.Allowlist)
+	})
 
 	// ─── Branches & Tags ─────────────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_refs",
 		Description: "Unified tool for listing, creating, and deleting branches and tags",
-	}, ManageRefsHandler(c))
+	}, func(c *bitbucket.Client) toolHandler[ManageRefsArgs] {
+		return ManageRefsHandler(c)
+	})
 
 	// ─── Commits ─────────────────────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_commits",
-		Description: "Unified tool for listing and getting commits, diffs, and diffstats",
-	}, ManageCommitsHandler(c))
+		Description: "Unified tool for listing and getting commits, diffs, diffstats, and build statuses",
+	}, func(c *bitbucket.Client) toolHandler[ManageCommitsArgs] {
+		return ManageCommitsHandler(c, opts.ResponseBudget)
+	})
 
 	// ─── Pull Requests ───────────────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_pull_requests",
-		Description: "Unified tool covering all pull request operations (list, get, create, update, merge, approve, unapprove, decline, diff, diffstat, commits)",
-	}, ManagePullRequestsHandler(c))
+		Description: "Unified tool covering all pull request operations (list, get, create, update, merge, merge when ready, approve, unapprove, request changes, decline, reviewers, diff, diffstat, commits, build statuses, activity)",
+	}, func(c *bitbucket.Client) toolHandler[ManagePullRequestsArgs] {
+		return ManagePullRequestsHandler(c, opts.ResponseBudget)
+	})
 
 	// ─── PR Comments ─────────────────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_pr_comments",
 		Description: "Unified tool for managing pull request comments (list, create, update, delete, resolve, unresolve)",
-	}, ManagePRCommentsHandler(c))
+	}, func(c *bitbucket.Client) toolHandler[ManagePRCommentsArgs] {
+		return ManagePRCommentsHandler(c)
+	})
+
+	// ─── PR Tasks ────────────────────────────────────────────────────
+	addTool(r, mcp.Tool{
+		Name:        "manage_pr_tasks",
+		Description: "Unified tool for managing pull request tasks (list, get, create, update, resolve, reopen, delete), optionally anchored to comments",
+	}, func(c *bitbucket.Client) toolHandler[ManagePRTasksArgs] {
+		return ManagePRTasksHandler(c)
+	})
 
 	// ─── Source / File Browsing ──────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_source",
 		Description: "Unified tool for source code operations (read, list_directory, get_history, search, write, delete)",
-	}, ManageSourceHandler(c))
+	}, func(c *bitbucket.Client) toolHandler[ManageSourceArgs] {
+		return ManageSourceHandler(c, opts.ResponseBudget)
+	})
 
 	// ─── Pipelines ───────────────────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_pipelines",
-		Description: "Unified tool for managing Bitbucket Pipelines (list, get, trigger, stop, list-steps, get-step-log)",
-	}, ManagePipelinesHandler(c))
+		Description: "Unified tool for managing Bitbucket Pipelines (list, get, trigger, stop, list-steps, get-step-log, wait)",
+	}, func(c *bitbucket.Client) toolHandler[ManagePipelinesArgs] {
+		return ManagePipelinesHandler(c, opts.ResponseBudget)
+	})
 
 	// ─── Issues ──────────────────────────────────────────────────────
-	addTool(s, disabled, tokenScopes, mcp.Tool{
+	addTool(r, mcp.Tool{
 		Name:        "manage_issues",
 		Description: "Unified tool for managing repository issues (list, get, create, update)",
-	}, ManageIssuesHandler(c))
+	}, func(c *bitbucket.Client) toolHandler[ManageIssuesArgs] {
+		return ManageIssuesHandler(c)
+	})
+
+	// ─── Profiles ────────────────────────────────────────────────────
+	if opts.Profiles != nil {
+		addTool(r, mcp.Tool{
+			Name:        "manage_profiles",
+			Description: "List the stored credential profiles other tools can act as through their profile argument (list, get)",
+		}, func(*bitbucket.Client) toolHandler[ManageProfilesArgs] {
+			return ManageProfilesHandler(opts.Profiles, r.currentProfile)
+		})
+	}
+
+	r.mu.Lock()
+	r.sync()
+	r.mu.Unlock()
+	if opts.Refresher != nil {
+		opts.Refresher.add(r)
+	}
+	if opts.Profiles != nil {
+		opts.Profiles.add(r)
+	}
 }
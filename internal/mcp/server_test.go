@@ -0,0 +1,268 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/zach-snell/bbkt/internal/audit"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+func TestHasRequiredScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		scopes   []string
+		required []string
+		want     bool
+	}{
+		{"no requirement", []string{"issue"}, nil, true},
+		{"unknown scopes allow everything", nil, []string{"repository:admin"}, true},
+
+		// OAuth scopes
+		{"oauth read", []string{"repository"}, []string{"repository"}, true},
+		{"oauth write implies read", []string{"repository:write"}, []string{"repository"}, true},
+		{"oauth read lacks write", []string{"repository"}, []string{"repository:write"}, false},
+		{"oauth admin lacks write", []string{"repository:admin"}, []string{"repository:write"}, false},
+		{"oauth admin lacks delete", []string{"repository:admin"}, []string{"repository:delete"}, false},
+		{"oauth delete", []string{"repository:delete"}, []string{"repository:delete"}, true},
+		{"oauth write lacks admin", []string{"repository:write"}, []string{"repository:admin"}, false},
+		{"oauth pr write", []string{"pullrequest:write"}, []string{"pullrequest:write"}, true},
+		{"oauth pr read lacks write", []string{"pullrequest"}, []string{"pullrequest:write"}, false},
+		{"oauth pipeline write implies read", []string{"pipeline:write"}, []string{"pipeline"}, true},
+		{"oauth pipeline read lacks write", []string{"pipeline"}, []string{"pipeline:write"}, false},
+		{"oauth issue read lacks write", []string{"issue"}, []string{"issue:write"}, false},
+		{"oauth unrelated scope", []string{"issue:write"}, []string{"pullrequest"}, false},
+
+		// API token scopes
+		{"token read", []string{"read:repository:bitbucket"}, []string{"repository"}, true},
+		{"token read lacks write", []string{"read:repository:bitbucket"}, []string{"repository:write"}, false},
+		{"token write", []string{"write:repository:bitbucket"}, []string{"repository:write"}, true},
+		{"token write lacks admin", []string{"write:repository:bitbucket"}, []string{"repository:admin"}, false},
+		{"token admin", []string{"admin:repository:bitbucket"}, []string{"repository:admin"}, true},
+		{"token admin lacks write", []string{"admin:repository:bitbucket"}, []string{"repository:write"}, false},
+		{"token delete", []string{"delete:repository:bitbucket"}, []string{"repository:delete"}, true},
+		{"token pr write", []string{"write:pullrequest:bitbucket"}, []string{"pullrequest:write"}, true},
+		{"token pr read lacks write", []string{"read:pullrequest:bitbucket"}, []string{"pullrequest:write"}, false},
+		{"token pipeline write", []string{"write:pipeline:bitbucket"}, []string{"pipeline:write"}, true},
+		{"token pipeline read lacks write", []string{"read:pipeline:bitbucket"}, []string{"pipeline:write"}, false},
+		{"token issue write implies read", []string{"write:issue:bitbucket"}, []string{"issue"}, true},
+		{"token issue read lacks write", []string{"read:issue:bitbucket"}, []string{"issue:write"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasRequiredScope(tt.scopes, tt.required); got != tt.want {
+				t.Errorf("hasRequiredScope(%v, %v) = %v, want %v", tt.scopes, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetActionRequiredScope(t *testing.T) {
+	tests := []struct {
+		tool, action string
+		want         []string
+	}{
+		{"manage_workspaces", "list", nil},
+		{"manage_repositories", "get", []string{"repository"}},
+		{"manage_repositories", "create", []string{"repository:admin"}},
+		{"manage_repositories", "delete", []string{"repository:delete"}},
+		{"manage_refs", "create-branch", []string{"repository:write"}},
+		{"manage_commits", "diff", []string{"repository"}},
+		{"manage_source", "write_file", []string{"repository:write"}},
+		{"manage_pull_requests", "get-diff", []string{"pullrequest"}},
+		{"manage_pull_requests", "merge", []string{"pullrequest:write"}},
+		{"manage_pull_requests", "approve", []string{"pullrequest:write"}},
+		{"manage_pr_comments", "resolve", []string{"pullrequest:write"}},
+		{"manage_pr_tasks", "list", []string{"pullrequest"}},
+		{"manage_pr_tasks", "reopen", []string{"pullrequest:write"}},
+		{"manage_pipelines", "wait", []string{"pipeline"}},
+		{"manage_pipelines", "trigger", []string{"pipeline:write"}},
+		{"manage_issues", "update", []string{"issue:write"}},
+	}
+
+	for _, tt := range tests {
+		if got := getActionRequiredScope(tt.tool, tt.action); !slices.Equal(got, tt.want) {
+			t.Errorf("getActionRequiredScope(%s, %s) = %v, want %v", tt.tool, tt.action, got, tt.want)
+		}
+	}
+}
+
+// TestWriteScopesCoverMutatingActions guards against a new mutating action
+// being advertised to read-only tokens.
+func TestWriteScopesCoverMutatingActions(t *testing.T) {
+	for tool, actions := range mutatingActions {
+		for action := range actions {
+			if _, ok := writeScopes[tool][action]; !ok {
+				t.Errorf("%s %s is mutating but has no write scope", tool, action)
+			}
+		}
+	}
+}
+
+func TestAllowedActions(t *testing.T) {
+	prActions := []string{"list", "get", "create", "update", "merge", "approve", "unapprove", "decline", "get-diff", "get-diffstat", "get-commits", "get-activity", "statuses"}
+	readOnly := []string{"list", "get", "get-diff", "get-diffstat", "get-commits", "get-activity", "statuses"}
+
+	tests := []struct {
+		name   string
+		scopes []string
+		want   []string
+	}{
+		{"oauth read-only", []string{"repository", "pullrequest"}, readOnly},
+		{"token read-only", []string{"read:repository:bitbucket", "read:pullrequest:bitbucket"}, readOnly},
+		{"oauth write", []string{"pullrequest:write"}, prActions},
+		{"token write", []string{"write:pullrequest:bitbucket"}, prActions},
+		{"unrelated scopes", []string{"issue"}, nil},
+		{"unknown scopes", nil, prActions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowedActions(tt.scopes, "manage_pull_requests", prActions); !slices.Equal(got, tt.want) {
+				t.Errorf("allowedActions(%v) = %v, want %v", tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestrictActions(t *testing.T) {
+	schema, err := jsonschema.For[ManagePipelinesArgs](nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restrictActions(schema, []string{"list", "get"})
+
+	prop := schema.Properties["action"]
+	if want := []any{"list", "get"}; !slices.Equal(prop.Enum, want) {
+		t.Errorf("enum = %v, want %v", prop.Enum, want)
+	}
+	if want := "Action to perform: 'list', 'get'"; prop.Description != want {
+		t.Errorf("description = %q, want %q", prop.Description, want)
+	}
+}
+
+func TestConfirmDestructivePreview(t *testing.T) {
+	tests := []struct {
+		tool, action string
+		preview      bool
+		confirm      bool
+	}{
+		{"manage_pull_requests", "merge", true, false},
+		{"manage_pull_requests", "merge", false, true},
+		{"manage_pull_requests", "merge-when-ready", true, true},
+		{"manage_pull_requests", "decline", true, true},
+		{"manage_repositories", "delete", true, true},
+		{"manage_refs", "delete-branch", true, true},
+		{"manage_source", "delete_file", true, true},
+		{"manage_pull_requests", "get", true, false},
+	}
+
+	for _, tt := range tests {
+		target := toolTarget{Action: tt.action, Workspace: "ws", RepoSlug: "repo", PRID: 1, Preview: tt.preview}
+		// Without a session there is no elicitation, so anything needing
+		// confirmation is refused.
+		msg := confirmDestructive(context.Background(), nil, ConfirmRefuse, tt.tool, target)
+		if got := msg != ""; got != tt.confirm {
+			t.Errorf("%s/%s preview=%v: needs confirmation = %v, want %v (%q)", tt.tool, tt.action, tt.preview, got, tt.confirm, msg)
+		}
+	}
+}
+
+func TestRecordCallPreview(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    string
+		action  string
+		out     any
+		audited bool
+	}{
+		{"decline ignores preview", "manage_pull_requests", "decline", nil, true},
+		{"create without generate_description", "manage_pull_requests", "create", &bitbucket.PullRequest{ID: 1}, true},
+		{"repository delete", "manage_repositories", "delete", nil, true},
+		{"merge-when-ready", "manage_pull_requests", "merge-when-ready", &bitbucket.MergeResult{Merged: true}, true},
+		{"merge checks only", "manage_pull_requests", "merge", &bitbucket.MergeResult{CheckOnly: true}, false},
+		{"drafted description", "manage_pull_requests", "create", &prDraft{Title: "t"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "audit.jsonl")
+			log, err := audit.Open(path, 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := &toolRegistry{opts: &Options{AuditLog: log}}
+			target := toolTarget{Action: tt.action, Workspace: "ws", RepoSlug: "repo", PRID: 1, Preview: true}
+			r.recordCall(tt.tool, target, nil, ToolResultText("ok"), tt.out, audit.OutcomeSuccess)
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := len(data) > 0; got != tt.audited {
+				t.Errorf("audited = %v, want %v", got, tt.audited)
+			}
+		})
+	}
+}
+
+func TestFilterPage(t *testing.T) {
+	page := &bitbucket.Paginated[bitbucket.Repository]{
+		Size:   40,
+		Page:   1,
+		Next:   "https://api.bitbucket.org/2.0/repositories/acme?page=2",
+		Values: []bitbucket.Repository{{Slug: "payments"}, {Slug: "secret"}, {Slug: "infra-dns"}},
+	}
+	allow, err := ParseAllowlist([]string{"acme/payments", "acme/infra-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filterPage(page, func(r bitbucket.Repository) bool { return allow.Allows("acme", r.Slug) })
+
+	var slugs []string
+	for _, r := range page.Values {
+		slugs = append(slugs, r.Slug)
+	}
+	if !slices.Equal(slugs, []string{"payments", "infra-dns"}) {
+		t.Errorf("kept %v", slugs)
+	}
+	if page.Size != 2 || page.PageLen != 2 || page.Next != "" || page.Previous != "" {
+		t.Errorf("pagination still counts hidden repositories: size %d, pagelen %d, next %q", page.Size, page.PageLen, page.Next)
+	}
+}
+
+func TestInlineTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    ManagePRCommentsArgs
+		want    bitbucket.InlineTarget
+		ok      bool
+		wantErr bool
+	}{
+		{"general comment", ManagePRCommentsArgs{}, bitbucket.InlineTarget{}, false, false},
+		{"file-level comment", ManagePRCommentsArgs{FilePath: "a.go"}, bitbucket.InlineTarget{Path: "a.go"}, false, false},
+		{"line", ManagePRCommentsArgs{FilePath: "a.go", Line: 3, EndLine: 5, Side: bitbucket.DiffSideOld}, bitbucket.InlineTarget{Path: "a.go", Line: 3, EndLine: 5, Side: bitbucket.DiffSideOld}, true, false},
+		{"legacy line_to", ManagePRCommentsArgs{FilePath: "a.go", LineTo: 7}, bitbucket.InlineTarget{Path: "a.go", Line: 7, Side: bitbucket.DiffSideNew}, true, false},
+		{"legacy line_from", ManagePRCommentsArgs{FilePath: "a.go", LineFrom: 2}, bitbucket.InlineTarget{Path: "a.go", Line: 2, Side: bitbucket.DiffSideOld}, true, false},
+		{"line without file_path", ManagePRCommentsArgs{Line: 3}, bitbucket.InlineTarget{Line: 3}, false, true},
+		{"end_line without file_path", ManagePRCommentsArgs{EndLine: 3}, bitbucket.InlineTarget{EndLine: 3}, false, true},
+		{"line_to without file_path", ManagePRCommentsArgs{LineTo: 3}, bitbucket.InlineTarget{}, false, true},
+		{"line_from without file_path", ManagePRCommentsArgs{LineFrom: 3}, bitbucket.InlineTarget{}, false, true},
+	}
+	for _, tt := range tests {
+		got, ok, err := inlineTarget(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s: got %+v, %v; want %+v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
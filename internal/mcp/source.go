@@ -24,10 +24,12 @@ type ManageSourceArgs struct {
 	MaxDepth  int    `json:"max_depth,omitempty" jsonschema:"Maximum depth of recursion (for list_directory)"`
 	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
 	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page"`
+	Cursor    string `json:"cursor,omitempty" jsonschema:"Continuation cursor from a truncated 'read_file' response"`
+	Format    string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
 // ManageSourceHandler handles the consolidated source file and directory operations.
-func ManageSourceHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolRequest, ManageSourceArgs) (*mcp.CallToolResult, any, error) {
+func ManageSourceHandler(c *bitbucket.Client, budget int) func(context.Context, *mcp.CallToolRequest, ManageSourceArgs) (*mcp.CallToolResult, any, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, args ManageSourceArgs) (*mcp.CallToolResult, any, error) {
 		switch args.Action {
 		case "read_file":
@@ -47,11 +49,15 @@ func ManageSourceHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if strings.Contains(contentType, "application/json") {
 				var prettyJSON interface{}
 				if err := json.Unmarshal(raw, &prettyJSON); err == nil {
-					data, _ := json.MarshalIndent(prettyJSON, "", "  ")
-					return ToolResultText(string(data)), nil, nil
+					raw, _ = json.MarshalIndent(prettyJSON, "", "  ")
 				}
 			}
-			return ToolResultText(string(raw)), nil, nil
+			key := fmt.Sprintf("file:%s/%s/%s/%s", args.Workspace, args.RepoSlug, args.Ref, args.Path)
+			text, err := budgetText(string(raw), key, args.Cursor, budget, boundaryLine)
+			if err != nil {
+				return ToolResultError(err.Error()), nil, nil
+			}
+			return ToolResultText(text), nil, nil
 
 		case "list_directory":
 			result, err := c.ListDirectory(bitbucket.ListDirectoryArgs{
@@ -65,8 +71,7 @@ func ManageSourceHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list directory: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, func() string { return treeMarkdown(result) })
 
 		case "get_history":
 			if args.Path == "" {
@@ -82,8 +87,7 @@ func ManageSourceHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get file history: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, result, nil)
 
 		case "search":
 			if args.Query == "" {
@@ -100,7 +104,7 @@ func ManageSourceHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 				return ToolResultError(fmt.Sprintf("failed to search code: %v", err)), nil, nil
 			}
 			var prettyJSON interface{}
-			if err := json.Unmarshal(raw, &prettyJSON); err == nil {
+			if err := json.Unmarshal(raw, &prettyJSON); err == nil && args.Format != formatRaw {
 				data, _ := json.MarshalIndent(prettyJSON, "", "  ")
 				return ToolResultText(string(data)), nil, nil
 			}
@@ -110,7 +114,7 @@ func ManageSourceHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if args.Path == "" || args.Content == "" || args.Message == "" {
 				return ToolResultError("path, content, and message are required for 'write_file' action"), nil, nil
 			}
-			err := c.WriteFile(bitbucket.WriteFileArgs{
+			commit, err := c.WriteFile(bitbucket.WriteFileArgs{
 				Workspace: args.Workspace,
 				RepoSlug:  args.RepoSlug,
 				Path:      args.Path,
@@ -122,13 +126,13 @@ func ManageSourceHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to write file: %v", err)), nil, nil
 			}
-			return ToolResultText(fmt.Sprintf("Successfully wrote %s", args.Path)), nil, nil
+			return ToolResultText(fmt.Sprintf("Successfully wrote %s%s", args.Path, committedIn(commit))), commit, nil
 
 		case "delete_file":
 			if args.Path == "" || args.Message == "" {
 				return ToolResultError("path and message are required for 'delete_file' action"), nil, nil
 			}
-			err := c.DeleteFile(bitbucket.DeleteFileArgs{
+			commit, err := c.DeleteFile(bitbucket.DeleteFileArgs{
 				Workspace: args.Workspace,
 				RepoSlug:  args.RepoSlug,
 				Path:      args.Path,
@@ -139,10 +143,18 @@ func ManageSourceHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToo
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to delete file: %v", err)), nil, nil
 			}
-			return ToolResultText(fmt.Sprintf("Successfully deleted %s", args.Path)), nil, nil
+			return ToolResultText(fmt.Sprintf("Successfully deleted %s%s", args.Path, committedIn(commit))), commit, nil
 
 		default:
 			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil
 		}
 	}
 }
+
+// committedIn names the commit a write or delete made, when Bitbucket reported it.
+func committedIn(commit *bitbucket.Commit) string {
+	if commit == nil {
+		return ""
+	}
+	return " in commit " + commit.Hash
+}
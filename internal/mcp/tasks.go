@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+type ManagePRTasksArgs struct {
+	Action    string `json:"action" jsonschema:"Action to perform: 'list', 'get', 'create', 'update', 'resolve', 'reopen', 'delete'" jsonschema_enum:"list,get,create,update,resolve,reopen,delete"`
+	Workspace string `json:"workspace" jsonschema:"Workspace slug"`
+	RepoSlug  string `json:"repo_slug" jsonschema:"Repository slug"`
+	PRID      int    `json:"pr_id" jsonschema:"Pull request ID"`
+	TaskID    int    `json:"task_id,omitempty" jsonschema:"Task ID (for 'get', 'update', 'resolve', 'reopen', 'delete')"`
+	Content   string `json:"content,omitempty" jsonschema:"Task description (for 'create', 'update')"`
+	CommentID int    `json:"comment_id,omitempty" jsonschema:"Comment ID to anchor the task to (for 'create')"`
+	State     string `json:"state,omitempty" jsonschema:"Filter by state: RESOLVED or UNRESOLVED (for 'list')"`
+	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
+	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Results per page (default 50)"`
+	Format    string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
+}
+
+// ManagePRTasksHandler handles the consolidated PR task operations.
+func ManagePRTasksHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolRequest, ManagePRTasksArgs) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, args ManagePRTasksArgs) (*mcp.CallToolResult, any, error) {
+		taskArgs := bitbucket.PRTaskArgs{
+			Workspace: args.Workspace,
+			RepoSlug:  args.RepoSlug,
+			PRID:      args.PRID,
+			TaskID:    args.TaskID,
+		}
+
+		switch args.Action {
+		case "list":
+			result, err := c.ListPRTasks(bitbucket.ListPRTasksArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				PRID:      args.PRID,
+				State:     strings.ToUpper(args.State),
+				Page:      args.Page,
+				Pagelen:   args.Pagelen,
+			})
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to list PR tasks: %v", err)), nil, nil
+			}
+			return render(args.Format, result, func() string { return tasksMarkdown(result) })
+
+		case "get":
+			if args.TaskID == 0 {
+				return ToolResultError("task_id is required for 'get' action"), nil, nil
+			}
+			task, err := c.GetPRTask(taskArgs)
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to get task: %v", err)), nil, nil
+			}
+			return render(args.Format, task, func() string { return taskMarkdown(task) })
+
+		case "create":
+			if args.Content == "" {
+				return ToolResultError("content is required for 'create' action"), nil, nil
+			}
+			task, err := c.CreatePRTask(bitbucket.CreatePRTaskArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				PRID:      args.PRID,
+				Content:   args.Content,
+				CommentID: args.CommentID,
+			})
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to create task: %v", err)), nil, nil
+			}
+			return render(args.Format, task, func() string { return taskMarkdown(task) })
+
+		case "update", "resolve", "reopen":
+			if args.TaskID == 0 {
+				return ToolResultError(fmt.Sprintf("task_id is required for '%s' action", args.Action)), nil, nil
+			}
+			update := bitbucket.UpdatePRTaskArgs{
+				Workspace: args.Workspace,
+				RepoSlug:  args.RepoSlug,
+				PRID:      args.PRID,
+				TaskID:    args.TaskID,
+				Content:   args.Content,
+			}
+			switch args.Action {
+			case "update":
+				if args.Content == "" {
+					return ToolResultError("content is required for 'update' action"), nil, nil
+				}
+			case "resolve":
+				update.State = bitbucket.TaskStateResolved
+			case "reopen":
+				update.State = bitbucket.TaskStateUnresolved
+			}
+			task, err := c.UpdatePRTask(update)
+			if err != nil {
+				return ToolResultError(fmt.Sprintf("failed to %s task: %v", args.Action, err)), nil, nil
+			}
+			return render(args.Format, task, func() string { return taskMarkdown(task) })
+
+		case "delete":
+			if args.TaskID == 0 {
+				return ToolResultError("task_id is required for 'delete' action"), nil, nil
+			}
+			if err := c.DeletePRTask(taskArgs); err != nil {
+				return ToolResultError(fmt.Sprintf("failed to delete task: %v", err)), nil, nil
+			}
+			return ToolResultText(fmt.Sprintf("Task #%d deleted successfully", args.TaskID)), nil, nil
+
+		default:
+			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/zach-snell/bbkt/internal/bitbucket"
+)
+
+// sessionIDHeader is the streamable HTTP transport's session header.
+const sessionIDHeader = "Mcp-Session-Id"
+
+// tenantHandler serves the streamable HTTP transport with one Bitbucket client
+// and tool set per session, built from the credentials each client presents.
+type tenantHandler struct {
+	opts       *Options
+	key        []byte
+	streamable *mcp.StreamableHTTPHandler
+}
+
+// tenant is the authenticated caller of a new session.
+type tenant struct {
+	client      *bitbucket.Client
+	user        string
+	fingerprint string
+}
+
+type tenantKey struct{}
+
+// NewMultiTenantHandler returns an HTTP handler where every MCP session
+// authenticates with its own Bitbucket credentials, sent in the Authorization
+// header as either "Bearer <access token>" or Basic "<email>:<API token>".
+// Each session gets a dedicated client and a tool set filtered by that
+// token's scopes. Sessions idle for longer than sessionTimeout are closed.
+func NewMultiTenantHandler(opts *Options, sessionTimeout time.Duration) http.Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+
+	h := &tenantHandler{opts: opts, key: key}
+	h.streamable = mcp.NewStreamableHTTPHandler(h.serverFor, &mcp.StreamableHTTPOptions{
+		SessionTimeout: sessionTimeout,
+	})
+	return h
+}
+
+func (h *tenantHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := r.Header.Get("Authorization")
+	client, user, err := clientFromAuthorization(header)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="bbkt", Basic realm="bbkt"`)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	fingerprint := h.fingerprint(header)
+
+	// Session IDs carry the fingerprint of the credentials that created them,
+	// so a leaked session ID is useless without the same credentials.
+	if sid := r.Header.Get(sessionIDHeader); sid != "" {
+		if !strings.HasSuffix(sid, "."+fingerprint) {
+			http.Error(w, "session does not belong to these credentials", http.StatusForbidden)
+			return
+		}
+		h.streamable.ServeHTTP(w, r)
+		return
+	}
+
+	if r.Method == http.MethodPost {
+		u, err := client.Verify()
+		if err != nil {
+			var apiErr *bitbucket.StatusError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+				http.Error(w, fmt.Sprintf("failed to verify Bitbucket credentials: %v", err), http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="bbkt", Basic realm="bbkt"`)
+			http.Error(w, "invalid Bitbucket credentials", http.StatusUnauthorized)
+			return
+		}
+		if u != nil {
+			user = u.Nickname
+			if user == "" {
+				user = u.DisplayName
+			}
+		}
+		t := &tenant{client: client, user: user, fingerprint: fingerprint}
+		r = r.WithContext(context.WithValue(r.Context(), tenantKey{}, t))
+	}
+	h.streamable.ServeHTTP(w, r)
+}
+
+// serverFor builds the MCP server for a new session from the tenant stored by ServeHTTP.
+func (h *tenantHandler) serverFor(r *http.Request) *mcp.Server {
+	t, _ := r.Context().Value(tenantKey{}).(*tenant)
+	if t == nil {
+		return nil
+	}
+
+	opts := *h.opts
+	opts.Profile = "http"
+	opts.User = t.user
+	// Sessions only ever act as the caller's own credentials.
+	opts.Profiles = nil
+
+	return newServer(t.client, &opts, &mcp.ServerOptions{
+		GetSessionID: func() string {
+			return randomID() + "." + t.fingerprint
+		},
+	})
+}
+
+func (h *tenantHandler) fingerprint(authorization string) string {
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(authorization))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// clientFromAuthorization builds a Bitbucket client from an Authorization header.
+// It returns the Basic auth username (if any) so audit entries can name the caller.
+func clientFromAuthorization(header string) (*bitbucket.Client, string, error) {
+	scheme, value, _ := strings.Cut(strings.TrimSpace(header), " ")
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, "", errors.New("missing Authorization header: send 'Bearer <access token>' or Basic '<email>:<API token>'")
+	}
+
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		return bitbucket.NewClient("", "", value), "", nil
+	case "basic":
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, "", errors.New("malformed Basic credentials")
+		}
+		username, password, ok := strings.Cut(string(decoded), ":")
+		if !ok || username == "" || password == "" {
+			return nil, "", errors.New("malformed Basic credentials: expected '<email>:<API token>'")
+		}
+		return bitbucket.NewClient(username, password, ""), username, nil
+	}
+	return nil, "", fmt.Errorf("unsupported authorization scheme %q", scheme)
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
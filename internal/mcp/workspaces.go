@@ -2,7 +2,6 @@ package mcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
@@ -14,22 +13,26 @@ type ManageWorkspacesArgs struct {
 	Workspace string `json:"workspace,omitempty" jsonschema:"Workspace slug or UUID (required for 'get')"`
 	Pagelen   int    `json:"pagelen,omitempty" jsonschema:"Number of results per page (default 25)"`
 	Page      int    `json:"page,omitempty" jsonschema:"Page number"`
+	Format    string `json:"format,omitempty" jsonschema:"Output format: 'markdown' (compact tables, default), 'json' (indented) or 'raw' (compact JSON)"`
 }
 
 // ManageWorkspacesHandler handles list and get operations for workspaces.
-func ManageWorkspacesHandler(c *bitbucket.Client) func(context.Context, *mcp.CallToolRequest, ManageWorkspacesArgs) (*mcp.CallToolResult, any, error) {
+func ManageWorkspacesHandler(c *bitbucket.Client, allow *Allowlist) func(context.Context, *mcp.CallToolRequest, ManageWorkspacesArgs) (*mcp.CallToolResult, any, error) {
 	return func(ctx context.Context, req *mcp.CallToolRequest, args ManageWorkspacesArgs) (*mcp.CallToolResult, any, error) {
 		switch args.Action {
 		case "list":
 			result, err := c.ListWorkspaces(bitbucket.ListWorkspacesArgs{
 				Pagelen: args.Pagelen,
 				Page:    args.Page,
+				All:     allow != nil,
 			})
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to list workspaces: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(result, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			if allow != nil {
+				filterPage(result, func(w bitbucket.Workspace) bool { return allow.AllowsWorkspace(w.Slug) })
+			}
+			return render(args.Format, result, func() string { return workspacesMarkdown(result) })
 
 		case "get":
 			if args.Workspace == "" {
@@ -41,8 +44,7 @@ func ManageWorkspacesHandler(c *bitbucket.Client) func(context.Context, *mcp.Cal
 			if err != nil {
 				return ToolResultError(fmt.Sprintf("failed to get workspace: %v", err)), nil, nil
 			}
-			data, _ := json.MarshalIndent(ws, "", "  ")
-			return ToolResultText(string(data)), nil, nil
+			return render(args.Format, ws, func() string { return workspaceMarkdown(ws) })
 
 		default:
 			return ToolResultError(fmt.Sprintf("unknown action: %s", args.Action)), nil, nil